@@ -0,0 +1,178 @@
+package blockchain
+
+import (
+	"bytes"
+	"sort"
+)
+
+// Maximum total transaction weight a block may carry
+const maxBlockWeight = 4000000
+
+// Coin-age priority of a transaction: the value of each input times the
+// confirmations of the transaction it spends, summed and divided by the
+// transaction's size. Old, large inputs give a high priority whatever the
+// fee. Coinbase transactions have none.
+func (tx *Transaction) Priority(bc *Blockchain) (float64, error) {
+	if tx.IsCoinbase() {
+		return 0, nil
+	}
+
+	total := 0
+	for _, in := range tx.Vin {
+		prevOut, err := bc.GetOutput(in.Txid, in.Vout)
+		if err != nil {
+			return 0, err
+		}
+
+		confirmations, err := bc.Confirmations(in.Txid)
+		if err != nil {
+			return 0, err
+		}
+
+		total += prevOut.Value * confirmations
+	}
+
+	return float64(total) / float64(tx.SerializedSize()), nil
+}
+
+// Decides which transactions go into a mined block
+type BlockAssembler interface {
+	// Returns the transactions to include without exceeding maxWeight, and
+	// the ones left out. Coinbase transactions must always be included.
+	SelectTransactions(bc *Blockchain, transactions []*Transaction, maxWeight int) ([]*Transaction, []*Transaction)
+}
+
+// The default assembler: takes transactions in order of fee per weight
+// unit. With WithPrioritySpace, transactions with a coin-age priority are
+// taken highest priority first until that much weight is used, before the
+// rest of the block is filled by fee rate.
+type FeeRateAssembler struct{}
+
+// Takes transactions in the order given, skipping any that no longer fit
+type FIFOAssembler struct{}
+
+// Picks the transactions to include in a block with the chain's assembler
+func (bc *Blockchain) selectTransactions(transactions []*Transaction, maxWeight int) ([]*Transaction, []*Transaction) {
+	return bc.assembler.SelectTransactions(bc, transactions, maxWeight)
+}
+
+// Separates the coinbase transactions, which always go in, from the rest.
+// Returns the coinbases, their weight and the candidates.
+func splitCoinbase(transactions []*Transaction) ([]*Transaction, int, []*Transaction) {
+	var coinbases, candidates []*Transaction
+	weight := 0
+
+	for _, tx := range transactions {
+		if tx.IsCoinbase() {
+			coinbases = append(coinbases, tx)
+			weight += tx.Weight()
+		} else {
+			candidates = append(candidates, tx)
+		}
+	}
+
+	return coinbases, weight, candidates
+}
+
+func (FIFOAssembler) SelectTransactions(bc *Blockchain, transactions []*Transaction, maxWeight int) ([]*Transaction, []*Transaction) {
+	selected, weight, candidates := splitCoinbase(transactions)
+
+	var excluded []*Transaction
+	for _, tx := range candidates {
+		if weight+tx.Weight() > maxWeight {
+			excluded = append(excluded, tx)
+			continue
+		}
+
+		selected = append(selected, tx)
+		weight += tx.Weight()
+	}
+
+	return selected, excluded
+}
+
+func (FeeRateAssembler) SelectTransactions(bc *Blockchain, transactions []*Transaction, maxWeight int) ([]*Transaction, []*Transaction) {
+	selected, weight, candidates := splitCoinbase(transactions)
+	var excluded []*Transaction
+
+	fees := make(map[*Transaction]int)
+	weights := make(map[*Transaction]int)
+	for _, tx := range candidates {
+		// transactions whose inputs can't be resolved are treated as paying nothing
+		fee, err := bc.TransactionFee(tx)
+		if err == nil {
+			fees[tx] = fee
+		}
+		weights[tx] = tx.Weight()
+	}
+
+	taken := make(map[*Transaction]bool)
+	if bc.prioritySpace > 0 {
+		priorities := make(map[*Transaction]float64)
+		for _, tx := range candidates {
+			// as with fees, unresolvable inputs count for nothing
+			priority, err := tx.Priority(bc)
+			if err == nil {
+				priorities[tx] = priority
+			}
+		}
+
+		byPriority := append([]*Transaction{}, candidates...)
+		sort.SliceStable(byPriority, func(i, j int) bool {
+			return priorities[byPriority[i]] > priorities[byPriority[j]]
+		})
+
+		reserved := 0
+		for _, tx := range byPriority {
+			if priorities[tx] <= 0 {
+				break
+			}
+			if reserved+weights[tx] > bc.prioritySpace || weight+weights[tx] > maxWeight {
+				continue
+			}
+
+			selected = append(selected, tx)
+			taken[tx] = true
+			reserved += weights[tx]
+			weight += weights[tx]
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		return fees[a]*weights[b] > fees[b]*weights[a]
+	})
+
+	for _, tx := range candidates {
+		if taken[tx] {
+			continue
+		}
+		if weight+weights[tx] > maxWeight {
+			excluded = append(excluded, tx)
+			continue
+		}
+
+		selected = append(selected, tx)
+		weight += weights[tx]
+	}
+
+	return selected, excluded
+}
+
+// Returns the transactions in the canonical block order: coinbase first,
+// then by ID. Nodes building a block from the same transactions must end up
+// with the same order, otherwise the block hash differs between them.
+func orderTransactions(transactions []*Transaction) []*Transaction {
+	ordered := append([]*Transaction{}, transactions...)
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, b := ordered[i], ordered[j]
+		if a.IsCoinbase() != b.IsCoinbase() {
+			return a.IsCoinbase()
+		}
+
+		return bytes.Compare(a.ID, b.ID) < 0
+	})
+
+	return ordered
+}
@@ -0,0 +1,150 @@
+package blockchain
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBlockOrderIsDeterministic(t *testing.T) {
+	transactions := []*Transaction{NewCoinbaseTX("alice", "", 1)}
+	for i := 0; i < 10; i++ {
+		tx := Transaction{nil, []TXInput{{[]byte(fmt.Sprintf("prev %d", i)), 0, "alice"}}, []TXOutput{{1, "bob"}}}
+		tx.SetID()
+		transactions = append(transactions, &tx)
+	}
+
+	want := newUnsealedBlock(transactions, nil, 8).HashTransactions()
+
+	for run := 0; run < 20; run++ {
+		shuffled := append([]*Transaction{}, transactions...)
+		rand.Shuffle(len(shuffled), func(i, j int) {
+			shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+		})
+
+		block := newUnsealedBlock(shuffled, nil, 8)
+		if got := block.HashTransactions(); !bytes.Equal(got, want) {
+			t.Fatalf("transactions hash %x for order %d, want %x", got, run, want)
+		}
+		if !block.Transactions[0].IsCoinbase() {
+			t.Fatalf("first transaction of order %d is not the coinbase", run)
+		}
+	}
+}
+
+func TestPriorityFavorsOldLargeInputs(t *testing.T) {
+	bc := newTestChain(t, WithAllocations(map[string]int{"alice": 1000}))
+	genesis := tipBlock(t, bc)
+	for i := 0; i < 5; i++ {
+		mineTestBlock(t, bc, "carol")
+	}
+	fresh := mineTestBlock(t, bc, "bob")
+
+	old := Transaction{nil, []TXInput{{genesis.Transactions[0].ID, 1, "alice"}}, []TXOutput{{1000, "dave"}}}
+	old.SetID()
+	small := Transaction{nil, []TXInput{{fresh.Transactions[0].ID, 0, "bob"}}, []TXOutput{{subsidy, "dave"}}}
+	small.SetID()
+
+	oldPriority, err := old.Priority(bc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	smallPriority, err := small.Priority(bc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oldPriority <= smallPriority {
+		t.Errorf("priority of an old large input %v, of a fresh small one %v", oldPriority, smallPriority)
+	}
+
+	// room for either transaction but not both; by fee rate alone the
+	// paying one goes in, the priority space takes the old input first
+	paying := spendTestTX(t, bc, "carol", "dave", 10, 20)
+	maxWeight := old.Weight() + paying.Weight() - 1
+
+	selected, _ := bc.selectTransactions([]*Transaction{&old, paying}, maxWeight)
+	if len(selected) != 1 || selected[0] != paying {
+		t.Errorf("assembler without priority space selected %d transactions, want only the paying one", len(selected))
+	}
+
+	bc = reopenTestChain(t, bc, WithPrioritySpace(old.Weight()))
+	selected, _ = bc.selectTransactions([]*Transaction{paying, &old}, maxWeight)
+	if len(selected) != 1 || selected[0] != &old {
+		t.Errorf("assembler with priority space selected %d transactions, want only the old input", len(selected))
+	}
+}
+
+func TestFeeRateAssemblerPicksHighestFees(t *testing.T) {
+	bc := newTestChain(t, WithAllocations(map[string]int{"ann": 60, "bob": 60, "cat": 60, "dan": 60}))
+
+	coinbase := NewCoinbaseTX("alice", "", 1)
+	var byFee []*Transaction
+	for i, from := range []string{"ann", "bob", "cat", "dan"} {
+		byFee = append(byFee, spendTestTX(t, bc, from, "frank", 1, 40-10*i))
+	}
+	for _, tx := range byFee[1:] {
+		if tx.Weight() != byFee[0].Weight() {
+			t.Fatalf("transactions weigh %d and %d, want them equal", byFee[0].Weight(), tx.Weight())
+		}
+	}
+	// room for the coinbase and two of the four
+	maxWeight := coinbase.Weight() + 2*byFee[0].Weight()
+	arrival := []*Transaction{coinbase, byFee[3], byFee[2], byFee[1], byFee[0]}
+
+	selected, excluded := FeeRateAssembler{}.SelectTransactions(bc, arrival, maxWeight)
+	if !reflect.DeepEqual(selected, []*Transaction{coinbase, byFee[0], byFee[1]}) {
+		t.Errorf("fee rate assembler selected %d transactions, want the coinbase and the two highest fees", len(selected))
+	}
+	if !reflect.DeepEqual(excluded, []*Transaction{byFee[2], byFee[3]}) {
+		t.Errorf("fee rate assembler excluded %d transactions, want the two lowest fees", len(excluded))
+	}
+
+	bc = reopenTestChain(t, bc, WithBlockAssembler(FIFOAssembler{}))
+	selected, _ = bc.selectTransactions(arrival, maxWeight)
+	if !reflect.DeepEqual(selected, []*Transaction{coinbase, byFee[3], byFee[2]}) {
+		t.Errorf("FIFO assembler selected %d transactions, want the coinbase and the first two to arrive", len(selected))
+	}
+}
+
+// Funds ann, bob and cat and returns a transaction from each, paying 30,
+// 20 and 10 in fees, each weighing 40% of maxBlockWeight so only two fit in
+// a block
+func heavyTestTXs(t *testing.T) (*Blockchain, []*Transaction) {
+	t.Helper()
+
+	bc := newTestChain(t, WithAllocations(map[string]int{"ann": 60, "bob": 60, "cat": 60}))
+	size := maxBlockWeight * 2 / 5 / weightPerByte
+
+	var txs []*Transaction
+	for i, from := range []string{"ann", "bob", "cat"} {
+		to := strings.Repeat(string(rune('x'+i)), size)
+		txs = append(txs, spendTestTX(t, bc, from, to, 1, 30-10*i))
+	}
+
+	return bc, txs
+}
+
+func TestAssemblyStopsAtWeightLimit(t *testing.T) {
+	bc, txs := heavyTestTXs(t)
+
+	excluded, err := bc.MineBlock([]*Transaction{NewCoinbaseTX("alice", "", 1), txs[2], txs[1], txs[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(excluded) != 1 || excluded[0] != txs[2] {
+		t.Errorf("MineBlock() excluded %d transactions, want only the lowest fee one", len(excluded))
+	}
+
+	block := tipBlock(t, bc)
+	if len(block.Transactions) != 3 {
+		t.Fatalf("mined block has %d transactions, want the coinbase and two", len(block.Transactions))
+	}
+	for _, want := range txs[:2] {
+		if _, err := bc.FindTransaction(want.ID); err != nil {
+			t.Errorf("FindTransaction(%x) = %v for a higher fee transaction", want.ID, err)
+		}
+	}
+}
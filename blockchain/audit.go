@@ -0,0 +1,71 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// One line of the audit log
+type auditEntry struct {
+	Hash         string `json:"hash"`
+	Height       int    `json:"height"`
+	Timestamp    int64  `json:"timestamp"`
+	Transactions int    `json:"transactions"`
+}
+
+// Appends a JSON line for every block added to the chain, kept apart from
+// the database so the record survives whatever happens to it
+type auditLog struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64
+}
+
+func (a *auditLog) record(block *Block, height int) error {
+	line, err := json.Marshal(auditEntry{hex.EncodeToString(block.Hash), height, block.Timestamp, len(block.Transactions)})
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := a.rotate(); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// Moves a log that has reached maxSize to path.1, replacing an older one
+func (a *auditLog) rotate() error {
+	if a.maxSize <= 0 {
+		return nil
+	}
+
+	info, err := os.Stat(a.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if info.Size() < a.maxSize {
+		return nil
+	}
+
+	return os.Rename(a.path, a.path+".1")
+}
@@ -0,0 +1,68 @@
+package blockchain
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func readAuditLog(t *testing.T, path string) []auditEntry {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var entries []auditEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry auditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("audit line %q: %s", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	return entries
+}
+
+func TestAuditLogRecordsAddedBlocks(t *testing.T) {
+	bc := newTestChain(t, WithAuditLog("audit.log", 0))
+
+	var blocks []*Block
+	blocks = append(blocks, mineTestBlock(t, bc, "bob", spendTestTX(t, bc, "alice", "bob", 4, 1)))
+	blocks = append(blocks, mineTestBlock(t, bc, "bob"))
+	blocks = append(blocks, mineTestBlock(t, bc, "bob"))
+
+	entries := readAuditLog(t, "audit.log")
+	if len(entries) != len(blocks) {
+		t.Fatalf("audit log has %d lines, want %d", len(entries), len(blocks))
+	}
+	for i, block := range blocks {
+		want := auditEntry{hex.EncodeToString(block.Hash), i + 1, block.Timestamp, len(block.Transactions)}
+		if entries[i] != want {
+			t.Errorf("audit line %d = %+v, want %+v", i, entries[i], want)
+		}
+	}
+}
+
+func TestAuditLogRotates(t *testing.T) {
+	// every line is longer than this, so each block starts a new file
+	bc := newTestChain(t, WithAuditLog("audit.log", 10))
+	mineTestBlock(t, bc, "bob")
+	last := mineTestBlock(t, bc, "bob")
+
+	if entries := readAuditLog(t, "audit.log.1"); len(entries) != 1 || entries[0].Height != 1 {
+		t.Errorf("rotated audit log = %+v, want the block at height 1", entries)
+	}
+	if entries := readAuditLog(t, "audit.log"); len(entries) != 1 || entries[0].Hash != hex.EncodeToString(last.Hash) {
+		t.Errorf("audit log = %+v, want only the last block", entries)
+	}
+}
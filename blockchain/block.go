@@ -3,14 +3,16 @@ package blockchain
 import (
 	"bytes"
 	"crypto/sha256"
+	"encoding/binary"
 	"encoding/gob"
 	"errors"
 	"fmt"
-	"log"
+	"io"
 	"math"
 	"math/big"
 	"os"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/boltdb/bolt"
@@ -22,9 +24,28 @@ const targetBits = 24
 // Database path
 const dbFile = "blockstore.db"
 
+// How long to wait for another process to release the database
+const dbOpenTimeout = 1 * time.Second
+
 // Database buckets
 const blocksBucket = "Blocks"
 
+var ErrNoBlockchain = errors.New("no existing blockchain found, create one first")
+
+var ErrBlockchainExists = errors.New("blockchain already exists")
+
+var ErrNotOnTip = errors.New("block does not extend the current chain tip")
+
+var ErrTransactionNotFound = errors.New("transaction is not found")
+
+var ErrBlockNotFound = errors.New("block is not found")
+
+var ErrDatabaseLocked = errors.New("blockchain database is in use by another process")
+
+var ErrWrongDifficulty = errors.New("block was not sealed at the chain's current difficulty")
+
+var ErrNonceExhausted = errors.New("no nonce up to the maximum meets the proof of work target")
+
 const genesisCoinbaseData = "The Times 03/Jan/2009 Chancellor on brink of second bailout for banks"
 
 type Block struct {
@@ -33,16 +54,94 @@ type Block struct {
 	PrevBlockHash []byte
 	Hash          []byte
 	Nonce         int
+
+	// Target bits the block was sealed at; 0 for blocks stored before
+	// difficulty was configurable, which were all mined at targetBits
+	Difficulty int
+
+	// The target the block was sealed at in compact form, see
+	// TargetToCompact; 0 for blocks stored before it was kept, whose
+	// target follows from Difficulty alone
+	Bits uint32
 }
 
 type Blockchain struct {
 	tip []byte
 	Db  *bolt.DB
+
+	// height of the tip block, genesis being 0
+	height int
+
+	// set by WithTipRepair
+	repairCorruptTip bool
+
+	// set when NewBlockchain had to reset the tip, see TipRepaired
+	tipRepaired bool
+
+	// gzip block records before storing them
+	compress bool
+
+	// encoding of new block records, kept in the meta bucket
+	format StorageFormat
+
+	// coinbase data for the genesis block when creating the chain
+	genesisData string
+
+	// balances paid out by the genesis block, see WithAllocations
+	allocations map[string]int
+
+	// seals mined blocks and verifies stored ones
+	consensus Consensus
+
+	// picks the transactions of mined blocks
+	assembler BlockAssembler
+
+	// set by WithDevMode
+	devMode bool
+
+	// target bits new blocks are mined at, kept in the meta bucket
+	difficulty int
+
+	// outputs already resolved by GetOutput
+	prevouts prevoutCache
+
+	// confirmations an output needs before it is spent, see WithMinConfirmations
+	minConfirmations int
+
+	// block weight set aside for high priority transactions, see WithPrioritySpace
+	prioritySpace int
+
+	// set by WithSeededNonce
+	seededNonce bool
+
+	// set by WithAuditLog
+	audit *auditLog
+
+	// set by WithMiningOutput
+	miningOutput io.Writer
+
+	// address balance subscriptions, see WatchAddress, and pending
+	// webhooks, see RegisterWebhook
+	watchMu   sync.Mutex
+	watchers  map[int]*addressWatch
+	nextWatch int
+	webhooks  []*webhook
+
+	// guards tip, height, and difficulty; held for writing only while they change
+	mu sync.RWMutex
 }
 
 type ProofOfWork struct {
 	block  *Block
 	target *big.Int
+	bits   int
+
+	// Run starts its search here and gives up below MaxNonce
+	StartNonce int
+	MaxNonce   int
+
+	// where Run reports progress, the package logger by default
+	log Logger
 }
 
 type BlockchainIterator struct {
@@ -50,85 +149,341 @@ type BlockchainIterator struct {
 	db          *bolt.DB
 }
 
-func NewBlock(transactions []*Transaction, prevBlockHash []byte) *Block {
-	block := &Block{
+// Builds a block and mines it. Fails rather than returning a block whose
+// hash doesn't meet the target if the nonce search is exhausted.
+func NewBlock(transactions []*Transaction, prevBlockHash []byte) (*Block, error) {
+	block := newUnsealedBlock(transactions, prevBlockHash, targetBits)
+	if err := (PoWConsensus{}).Seal(block); err != nil {
+		return nil, err
+	}
+
+	return block, nil
+}
+
+// Builds a block that still has to be sealed by a Consensus
+func newUnsealedBlock(transactions []*Transaction, prevBlockHash []byte, difficulty int) *Block {
+	return &Block{
 		Timestamp:     time.Now().Unix(),
-		Transactions:  transactions,
+		Transactions:  orderTransactions(transactions),
 		PrevBlockHash: prevBlockHash,
 		Hash:          []byte{},
 		Nonce:         0,
+		Difficulty:    difficulty,
+		Bits:          TargetToCompact(targetForBits(difficulty)),
 	}
-	pow := NewProofOfWork(block)
-	nonce, hash := pow.Run()
-
-	block.Hash = hash
-	block.Nonce = nonce
+}
 
-	return block
+// Builds a block from the given field values as they are, without sealing
+// it or ordering its transactions. Meant for tests feeding arbitrary blocks
+// through serialization; such a block is not valid on any chain.
+func BlockFromFields(timestamp int64, transactions []*Transaction, prevBlockHash, hash []byte, nonce, difficulty int, bits uint32) *Block {
+	return &Block{
+		Timestamp:     timestamp,
+		Transactions:  transactions,
+		PrevBlockHash: prevBlockHash,
+		Hash:          hash,
+		Nonce:         nonce,
+		Difficulty:    difficulty,
+		Bits:          bits,
+	}
 }
 
-func (b *Block) Serialize() []byte {
+func (b *Block) Serialize() ([]byte, error) {
 	var result bytes.Buffer
 
-	encoder := gob.NewEncoder(&result)
-	if err := encoder.Encode(b); err != nil {
-		log.Fatal(err)
+	if _, err := b.WriteTo(&result); err != nil {
+		return nil, err
 	}
 
-	return result.Bytes()
+	return result.Bytes(), nil
+}
+
+// Streams the gob encoding of the block to w without buffering it first
+func (b *Block) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+
+	encoder := gob.NewEncoder(cw)
+	err := encoder.Encode(b)
+
+	return cw.n, err
 }
 
-func DeseralizeBlock(d []byte) *Block {
+// Decodes a single block streamed by Block.WriteTo
+func ReadBlockFrom(r io.Reader) (*Block, error) {
 	var block Block
 
-	decoder := gob.NewDecoder(bytes.NewReader(d))
+	decoder := gob.NewDecoder(r)
 	if err := decoder.Decode(&block); err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	return &block
+	return &block, nil
 }
 
-func (bc *Blockchain) MineBlock(transactions []*Transaction) {
-	var lastHash []byte
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
 
-	err := bc.Db.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(blocksBucket))
-		lastHash = b.Get([]byte("l"))
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
 
-		return nil
-	})
+	return n, err
+}
 
-	if err != nil {
-		log.Panic(err)
+func DeseralizeBlock(d []byte) (*Block, error) {
+	return deserializeBlock(d)
+}
+
+func deserializeBlock(d []byte) (*Block, error) {
+	return ReadBlockFrom(bytes.NewReader(d))
+}
+
+// Mines the given transactions into a new block at the tip. Coinbase
+// transactions are always included; the rest are picked by the chain's
+// BlockAssembler, by default highest fee rate first until maxBlockWeight is
+// reached. Transactions that didn't fit are returned so the caller can keep
+// them for a later block.
+//
+// Mining runs without holding the chain lock so reads aren't blocked while
+// a nonce is searched for. If the tip moves in the meantime the block is
+// mined again on top of the new tip, its coinbases rewritten for the new
+// height.
+func (bc *Blockchain) MineBlock(transactions []*Transaction) ([]*Transaction, error) {
+	transactions, excluded := bc.selectTransactions(transactions, maxBlockWeight)
+
+	for {
+		bc.mu.RLock()
+		lastHash := bc.tip
+		height := bc.height + 1
+		difficulty := bc.difficulty
+		bc.mu.RUnlock()
+
+		// coinbases are built for the height at the time of the call, which
+		// is out of date once the tip has moved
+		newBlock := newUnsealedBlock(withCoinbaseHeight(transactions, height), lastHash, difficulty)
+
+		// blocks mined within the same second would otherwise not move
+		// past the median time
+		medianTime, err := bc.medianTimePastAt(lastHash)
+		if err != nil {
+			return nil, err
+		}
+		if newBlock.Timestamp <= medianTime {
+			newBlock.Timestamp = medianTime + 1
+		}
+
+		if err := bc.seal(newBlock); err != nil {
+			return nil, err
+		}
+
+		err = bc.AddBlock(newBlock)
+		if err == ErrNotOnTip {
+			logger.Infof("Chain tip changed while mining, mining again")
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return excluded, nil
 	}
+}
+
+// Validates a block extending the current tip and stores it as the new tip
+func (bc *Blockchain) AddBlock(block *Block) error {
+	return bc.addBlock(block, nil)
+}
 
-	newBlock := NewBlock(transactions, lastHash)
+// AddBlock that calls commit, if set, once the block is written and rolls
+// it back if commit returns an error
+func (bc *Blockchain) addBlock(block *Block, commit func() error) error {
+	bc.mu.Lock()
 
-	err = bc.Db.Update(func(tx *bolt.Tx) error {
+	var height int
+	err := bc.Db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(blocksBucket))
-		err := b.Put(newBlock.Hash, newBlock.Serialize())
+		if !bytes.Equal(b.Get([]byte("l")), block.PrevBlockHash) {
+			return ErrNotOnTip
+		}
+
+		prev, err := decodeBlockRecord(b.Get(block.PrevBlockHash))
 		if err != nil {
-			log.Panic(err)
+			return err
+		}
+		if err := checkTarget(block, bc.difficulty); err != nil {
+			return err
+		}
+		if err := bc.validateBlock(block, prev); err != nil {
+			return err
+		}
+		if err := checkHasCoinbase(block); err != nil {
+			return err
+		}
+		if err := checkTransactionLimits(block); err != nil {
+			return err
+		}
+		if err := checkBlockWeight(block); err != nil {
+			return err
+		}
+		medianTime, err := medianTimePast(b, block.PrevBlockHash)
+		if err != nil {
+			return err
+		}
+		if block.Timestamp <= medianTime {
+			return ErrTimeTooOld
+		}
+		if err := checkCoinbaseHeight(block, bc.height+1); err != nil {
+			return err
+		}
+		if err := checkDuplicateTransactions(tx, block); err != nil {
+			return err
 		}
 
-		err = b.Put([]byte("l"), newBlock.Hash)
+		record, err := encodeBlockRecord(block, bc.format, bc.compress)
 		if err != nil {
-			log.Panic(err)
+			return err
+		}
+
+		err = b.Put(block.Hash, record)
+		if err != nil {
+			return err
+		}
+
+		err = b.Put([]byte("l"), block.Hash)
+		if err != nil {
+			return err
+		}
+
+		if err := indexTransactions(tx, block); err != nil {
+			return err
+		}
+
+		if err := indexHeight(tx, bc.height+1, block.Hash); err != nil {
+			return err
+		}
+
+		if commit != nil {
+			if err := commit(); err != nil {
+				return err
+			}
 		}
 
-		bc.tip = newBlock.Hash
+		bc.tip = block.Hash
+		bc.height++
+		height = bc.height
 
 		return nil
 	})
+	bc.mu.Unlock()
 
 	if err != nil {
-		log.Panic(err)
+		return err
+	}
+
+	bc.prevouts.removeSpent(block)
+
+	if bc.audit != nil {
+		// the block is stored either way, so a failed write doesn't fail AddBlock
+		if err := bc.audit.record(block, height); err != nil {
+			logger.Errorf("Writing block %x to the audit log: %s", block.Hash, err)
+		}
 	}
+
+	// outside the lock, since watchers read the chain to compute balances
+	bc.notifyWatchers(block)
+	bc.notifyWebhooks(height)
+
+	return nil
+}
+
+// Returns the number of blocks in the chain, including genesis
+func (bc *Blockchain) GetBlockCount() (int, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	return bc.height + 1, nil
+}
+
+// Whether the tip was inconsistent when the chain was opened and had to be
+// reset by RepairTip
+func (bc *Blockchain) TipRepaired() bool {
+	return bc.tipRepaired
+}
+
+func (bc *Blockchain) DevMode() bool {
+	return bc.devMode
+}
+
+func (bc *Blockchain) GetBestBlockHash() []byte {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	return append([]byte{}, bc.tip...)
+}
+
+func (bc *Blockchain) FindTransaction(ID []byte) (Transaction, error) {
+	if blockHash, err := bc.GetTransactionLocation(ID); err == nil {
+		if block, err := bc.GetBlock(blockHash); err == nil {
+			for _, tx := range block.Transactions {
+				if bytes.Equal(tx.ID, ID) {
+					return *tx, nil
+				}
+			}
+		}
+	}
+
+	// fall back to scanning for databases whose index hasn't been built
+	bci := bc.Iterator()
+
+	for {
+		block, err := bci.Next()
+		if err != nil {
+			return Transaction{}, err
+		}
+
+		for _, tx := range block.Transactions {
+			if bytes.Equal(tx.ID, ID) {
+				return *tx, nil
+			}
+		}
+
+		if len(block.PrevBlockHash) == 0 {
+			break
+		}
+	}
+
+	return Transaction{}, ErrTransactionNotFound
+}
+
+// Returns how many blocks confirm the transaction: 1 when it is in the tip
+// block, one more for every block mined on top of it
+func (bc *Blockchain) Confirmations(txid []byte) (int, error) {
+	bci := bc.Iterator()
+
+	for depth := 0; ; depth++ {
+		block, err := bci.Next()
+		if err != nil {
+			return 0, err
+		}
+
+		for _, tx := range block.Transactions {
+			if bytes.Equal(tx.ID, txid) {
+				return depth + 1, nil
+			}
+		}
+
+		if len(block.PrevBlockHash) == 0 {
+			break
+		}
+	}
+
+	return 0, ErrTransactionNotFound
 }
 
 // Blockchain needs an inital "Genesis" block to start
-func NewGenesisBlock(coinbase *Transaction) *Block {
+func NewGenesisBlock(coinbase *Transaction) (*Block, error) {
 	return NewBlock([]*Transaction{coinbase}, []byte{})
 }
 
@@ -140,76 +495,249 @@ func dbExists() bool {
 	return true
 }
 
-func NewBlockchain(address string) *Blockchain {
+// Opens the database file, giving up after dbOpenTimeout if another process
+// holds it
+func openDB() (*bolt.DB, error) {
+	return openDBAt(dbFile)
+}
+
+func openDBAt(path string) (*bolt.DB, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: dbOpenTimeout})
+	if err == bolt.ErrTimeout {
+		return nil, ErrDatabaseLocked
+	}
+
+	return db, err
+}
+
+func NewBlockchain(address string, opts ...Option) (*Blockchain, error) {
 	if dbExists() == false {
-		fmt.Println("No existing blockchain found. Create one first")
-		os.Exit(1)
+		return nil, ErrNoBlockchain
 	}
 
 	var tip []byte
-	db, err := bolt.Open(dbFile, 0600, nil)
+	db, err := openDB()
 	if err != nil {
-		log.Panic(err)
+		return nil, err
 	}
 
+	tipReadable := false
+	difficulty := targetBits
+	format := FormatGob
+	// chains created before the consensus was stored aren't checked
+	storedConsensus := -1
 	err = db.Update(func(tx *bolt.Tx) error {
+		if err := migrate(tx); err != nil {
+			return err
+		}
+
+		if bits, ok := getMetaInt(tx, difficultyKey); ok {
+			difficulty = bits
+		}
+		if stored, ok := getMetaInt(tx, storageFormatKey); ok {
+			format = StorageFormat(stored)
+		}
+		if stored, ok := getMetaInt(tx, consensusKey); ok {
+			storedConsensus = stored
+		}
+
 		b := tx.Bucket([]byte(blocksBucket))
-		tip = b.Get([]byte("l"))
+		// copied because the value is only valid for the life of the transaction
+		tip = append([]byte{}, b.Get([]byte("l"))...)
+
+		if encodedTip := b.Get(tip); encodedTip != nil {
+			_, err := decodeBlockRecord(encodedTip)
+			tipReadable = err == nil
+		}
 
 		return nil
 	})
 
 	if err != nil {
-		log.Fatal(err)
+		db.Close()
+		return nil, err
+	}
+
+	bc := Blockchain{tip: tip, Db: db, consensus: PoWConsensus{}, assembler: FeeRateAssembler{}, prevouts: prevoutCache{size: defaultPrevoutCacheSize}}
+	for _, opt := range opts {
+		opt(&bc)
+	}
+
+	if kind := consensusKind(bc.consensus); storedConsensus >= 0 && kind != storedConsensus {
+		db.Close()
+		return nil, fmt.Errorf("%w: it uses %s, not %s", ErrConsensusMismatch, consensusName(storedConsensus), consensusName(kind))
 	}
 
-	bc := Blockchain{tip, db}
+	if !tipReadable && !bc.repairCorruptTip {
+		db.Close()
+		return nil, fmt.Errorf("%w (tip %x)", ErrCorruptTip, tip)
+	}
+	// the stored difficulty and format win over WithDifficulty and WithStorageFormat
+	bc.difficulty = difficulty
+	bc.format = format
+
+	if err := bc.checkDevMode(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	repaired, err := bc.RepairTip()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if repaired {
+		logger.Infof("Chain tip was inconsistent, reset to %x", bc.tip)
+	}
+	bc.tipRepaired = repaired
 
-	return &bc
+	return &bc, nil
 }
 
-func CreateBlockchain(address string) *Blockchain {
+func CreateBlockchain(address string, opts ...Option) (*Blockchain, error) {
 	if dbExists() {
-		fmt.Println("Blockchain already exists")
-		os.Exit(1)
+		return nil, ErrBlockchainExists
 	}
 
-	var tip []byte
-	db, err := bolt.Open(dbFile, 0600, nil)
+	return createBlockchainAt(dbFile, address, opts...)
+}
+
+// Creates a chain in a new database file at path
+func createBlockchainAt(path, address string, opts ...Option) (*Blockchain, error) {
+	bc := Blockchain{genesisData: genesisCoinbaseData, consensus: PoWConsensus{}, assembler: FeeRateAssembler{}, difficulty: targetBits, prevouts: prevoutCache{size: defaultPrevoutCacheSize}}
+	for _, opt := range opts {
+		opt(&bc)
+	}
+
+	// checked before the database file exists, so a bad setting doesn't
+	// leave an empty chain behind
+	if err := bc.checkDevMode(); err != nil {
+		return nil, err
+	}
+	if bc.difficulty < minDifficulty || bc.difficulty > maxDifficulty {
+		return nil, ErrDifficultyRange
+	}
+
+	db, err := openDBAt(path)
 	if err != nil {
-		log.Panic(err)
+		return nil, err
 	}
+	bc.Db = db
 
 	err = db.Update(func(tx *bolt.Tx) error {
-		cbtx := NewCoinbaseTX(address, genesisCoinbaseData)
-		genesis := NewGenesisBlock(cbtx)
+		cbtx := NewCoinbaseTX(address, bc.genesisData, 0)
+		if len(bc.allocations) > 0 {
+			cbtx.addAllocations(bc.allocations)
+		}
+		genesis := newUnsealedBlock([]*Transaction{cbtx}, []byte{}, bc.difficulty)
+		if err := bc.seal(genesis); err != nil {
+			return err
+		}
 
 		b, err := tx.CreateBucket([]byte(blocksBucket))
 		if err != nil {
-			log.Panic(err)
+			return err
 		}
 
-		err = b.Put(genesis.Hash, genesis.Serialize())
+		record, err := encodeBlockRecord(genesis, bc.format, bc.compress)
 		if err != nil {
-			log.Panic(err)
+			return err
 		}
 
-		err = b.Put([]byte("l"), genesis.Hash)
-		if err != nil {
-			log.Panic(err)
+		if err := b.Put(genesis.Hash, record); err != nil {
+			return err
+		}
+
+		if err := b.Put([]byte("l"), genesis.Hash); err != nil {
+			return err
+		}
+
+		if err := indexTransactions(tx, genesis); err != nil {
+			return err
+		}
+
+		if err := indexHeight(tx, 0, genesis.Hash); err != nil {
+			return err
 		}
-		tip = genesis.Hash
+
+		if err := putMetaInt(tx, schemaVersionKey, schemaVersion); err != nil {
+			return err
+		}
+
+		if err := putMetaInt(tx, difficultyKey, bc.difficulty); err != nil {
+			return err
+		}
+
+		if err := putMetaInt(tx, storageFormatKey, int(bc.format)); err != nil {
+			return err
+		}
+
+		if err := putMetaInt(tx, consensusKey, consensusKind(bc.consensus)); err != nil {
+			return err
+		}
+		bc.tip = genesis.Hash
 
 		return nil
 	})
 
 	if err != nil {
-		log.Panic(err)
+		removeDB(db)
+		return nil, err
 	}
 
-	bc := Blockchain{tip, db}
+	return &bc, nil
+}
+
+// Closes and deletes a database whose chain could not be created, so it
+// isn't mistaken for an existing chain
+func removeDB(db *bolt.DB) {
+	path := db.Path()
+	db.Close()
 
-	return &bc
+	if err := os.Remove(path); err != nil {
+		logger.Errorf("Removing %s: %s", path, err)
+	}
+}
+
+// Replaces the chain database with a new chain holding only a genesis
+// block that pays address. Only allowed in DevMode; opts apply to the new
+// chain as they would for CreateBlockchain. The new chain is built next to
+// the old one and only moved over it once created, so a bad option leaves
+// the existing chain as it was.
+func ResetBlockchain(address string, opts ...Option) (*Blockchain, error) {
+	bc, err := NewBlockchain(address, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bc.devMode {
+		bc.Db.Close()
+		return nil, ErrDevModeRequired
+	}
+
+	path := bc.Db.Path()
+	if err := bc.Db.Close(); err != nil {
+		return nil, err
+	}
+
+	// left behind if an earlier reset was interrupted
+	resetPath := path + ".reset"
+	if err := os.Remove(resetPath); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	created, err := createBlockchainAt(resetPath, address, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := created.Db.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(resetPath, path); err != nil {
+		return nil, err
+	}
+
+	return NewBlockchain(address, opts...)
 }
 
 func (b *Block) HashTransactions() []byte {
@@ -227,36 +755,45 @@ func (b *Block) HashTransactions() []byte {
 
 // Specifies the requirements for the hash of a given block
 func NewProofOfWork(b *Block) *ProofOfWork {
-	target := big.NewInt(1)
-	target.Lsh(target, uint(256-targetBits))
+	bits := blockDifficulty(b)
 
-	pow := &ProofOfWork{b, target}
+	target := targetForBits(bits)
+	if b.Bits != 0 {
+		target = CompactToTarget(b.Bits)
+	}
+
+	pow := &ProofOfWork{block: b, target: target, bits: bits, MaxNonce: math.MaxInt64, log: logger}
 
 	return pow
 }
 
 func (pow *ProofOfWork) prepareData(nonce int) []byte {
-	data := bytes.Join([][]byte{
+	fields := [][]byte{
 		pow.block.PrevBlockHash,
 		pow.block.HashTransactions(),
 		[]byte(strconv.FormatInt(pow.block.Timestamp, 10)),
 		[]byte(strconv.FormatInt(int64(nonce), 10)),
-		[]byte(strconv.FormatInt(int64(targetBits), 10)),
-	},
-		[]byte{},
-	)
+		[]byte(strconv.FormatInt(int64(pow.bits), 10)),
+	}
+	// left out when unset so blocks from before Bits keep their hashes
+	if pow.block.Bits != 0 {
+		fields = append(fields, []byte(strconv.FormatUint(uint64(pow.block.Bits), 10)))
+	}
 
-	return data
+	return bytes.Join(fields, []byte{})
 }
 
-func (pow *ProofOfWork) Run() (int, []byte) {
+// Searches nonces from StartNonce up to MaxNonce for a hash below the target.
+// Returns ErrNonceExhausted if none of them works; the caller can change the
+// block (e.g. its timestamp) and try again.
+func (pow *ProofOfWork) Run() (int, []byte, error) {
 	var hashInt big.Int
 	var hash [32]byte
-	nonce := 0
-	maxNonce := math.MaxInt64
+	nonce := pow.StartNonce
+	found := false
 
-	fmt.Printf("Mining new block\n")
-	for nonce < maxNonce {
+	pow.log.Infof("Mining new block")
+	for nonce < pow.MaxNonce {
 		//compute block hash
 		data := pow.prepareData(nonce)
 		hash = sha256.Sum256(data)
@@ -264,15 +801,37 @@ func (pow *ProofOfWork) Run() (int, []byte) {
 
 		//checking hash requirements
 		if hashInt.Cmp(pow.target) == -1 {
+			found = true
 			break
 		} else {
 			nonce++
 		}
 
+		if tried := nonce - pow.StartNonce; tried%100000 == 0 {
+			pow.log.Debugf("Tried %d nonces", tried)
+		}
+
+	}
+
+	if !found {
+		return 0, nil, ErrNonceExhausted
 	}
-	fmt.Printf("%x\n", hash)
+	pow.log.Infof("%x", hash)
 
-	return nonce, hash[:]
+	return nonce, hash[:], nil
+}
+
+// A nonce to start the search from that only depends on the block's other
+// fields, so the same block always gets the same start
+func (pow *ProofOfWork) seededStart() int {
+	seed := sha256.Sum256(bytes.Join([][]byte{
+		pow.block.PrevBlockHash,
+		pow.block.HashTransactions(),
+		[]byte(strconv.FormatInt(pow.block.Timestamp, 10)),
+		[]byte(strconv.FormatInt(int64(pow.bits), 10)),
+	}, []byte{}))
+
+	return int(binary.BigEndian.Uint32(seed[:4]))
 }
 
 func (pow *ProofOfWork) Validate() bool {
@@ -287,13 +846,64 @@ func (pow *ProofOfWork) Validate() bool {
 	return isValid
 }
 
+// Number of leading zero bits a block hash needs to meet the target
+func (pow *ProofOfWork) TargetBits() int {
+	return pow.bits
+}
+
+// Returns the target as a 64 character hex string, the same width as a block hash
+func (pow *ProofOfWork) TargetHex() string {
+	return fmt.Sprintf("%064x", pow.target)
+}
+
+// Returns the target in compact form, see TargetToCompact
+func (pow *ProofOfWork) CompactTarget() uint32 {
+	return TargetToCompact(pow.target)
+}
+
+// Returns how many times harder the target is to meet than the easiest
+// one, at minDifficulty bits, so difficulties can be compared as a single
+// number. Each extra target bit doubles it.
+func (pow *ProofOfWork) Difficulty() float64 {
+	maxTarget := new(big.Int).Lsh(big.NewInt(1), uint(256-minDifficulty))
+
+	ratio, _ := new(big.Float).Quo(new(big.Float).SetInt(maxTarget), new(big.Float).SetInt(pow.target)).Float64()
+
+	return ratio
+}
+
+func (bc *Blockchain) GetBlock(blockHash []byte) (*Block, error) {
+	var block *Block
+
+	err := bc.Db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(blocksBucket))
+
+		encodedBlock := b.Get(blockHash)
+		if encodedBlock == nil {
+			return ErrBlockNotFound
+		}
+
+		decoded, err := decodeBlockRecord(encodedBlock)
+		if err != nil {
+			return err
+		}
+		block = decoded
+
+		return nil
+	})
+
+	return block, err
+}
+
 func (bc *Blockchain) Iterator() *BlockchainIterator {
+	bc.mu.RLock()
 	bci := &BlockchainIterator{bc.tip, bc.Db}
+	bc.mu.RUnlock()
 
 	return bci
 }
 
-func (i *BlockchainIterator) Next() *Block {
+func (i *BlockchainIterator) Next() (*Block, error) {
 	var block *Block
 
 	//fetch the current block from db
@@ -304,15 +914,19 @@ func (i *BlockchainIterator) Next() *Block {
 		}
 
 		encodedBlock := b.Get(i.currentHash)
-		block = DeseralizeBlock(encodedBlock)
+		decoded, err := decodeBlockRecord(encodedBlock)
+		if err != nil {
+			return err
+		}
+		block = decoded
 		i.currentHash = block.PrevBlockHash
 
 		return nil
 	})
 
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	return block
+	return block, nil
 }
@@ -0,0 +1,430 @@
+package blockchain
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Instant seal that waits in Seal until released, standing in for a slow
+// nonce search
+type blockingSeal struct {
+	instantSeal
+	sealing chan struct{}
+	release chan struct{}
+}
+
+func (c blockingSeal) Seal(block *Block) error {
+	c.sealing <- struct{}{}
+	<-c.release
+
+	return c.instantSeal.Seal(block)
+}
+
+func TestReadsDoNotWaitForMining(t *testing.T) {
+	c := blockingSeal{sealing: make(chan struct{}), release: make(chan struct{})}
+	bc := newTestChain(t, WithDevMode(), WithInstantSeal())
+	bc.consensus = c
+
+	mined := make(chan error)
+	go func() {
+		_, err := bc.MineBlock([]*Transaction{NewCoinbaseTX("bob", "", 1)})
+		mined <- err
+	}()
+	<-c.sealing
+
+	read := make(chan int)
+	go func() {
+		outPoints, _ := bc.UTXOsForAddress("alice")
+		read <- totalValue(outPoints)
+	}()
+
+	select {
+	case got := <-read:
+		if got != subsidy {
+			t.Errorf("balance = %d while mining, want %d", got, subsidy)
+		}
+	case <-time.After(time.Second):
+		t.Error("reading a balance waited for the block being mined")
+	}
+
+	close(c.release)
+	if err := <-mined; err != nil {
+		t.Fatal(err)
+	}
+	if got := balance(t, bc, "bob"); got != subsidy {
+		t.Errorf("balance of bob = %d after mining, want %d", got, subsidy)
+	}
+}
+
+func TestGetBlockCountAndBestBlockHash(t *testing.T) {
+	bc := newTestChain(t)
+
+	if count, err := bc.GetBlockCount(); err != nil || count != 1 {
+		t.Errorf("GetBlockCount() = %d, %v for a new chain, want 1", count, err)
+	}
+
+	const added = 3
+	for i := 0; i < added; i++ {
+		block := mineTestBlock(t, bc, "bob")
+
+		if !bytes.Equal(bc.GetBestBlockHash(), block.Hash) {
+			t.Errorf("GetBestBlockHash() = %x, want the block just mined %x", bc.GetBestBlockHash(), block.Hash)
+		}
+	}
+
+	if count, err := bc.GetBlockCount(); err != nil || count != added+1 {
+		t.Errorf("GetBlockCount() = %d, %v, want %d", count, err, added+1)
+	}
+}
+
+func TestBlockRoundTripThroughPipe(t *testing.T) {
+	SetLogger(NopLogger)
+	block := sealedTestBlock(t, nil)
+
+	r, w := io.Pipe()
+	go func() {
+		_, err := block.WriteTo(w)
+		w.CloseWithError(err)
+	}()
+
+	got, err := ReadBlockFrom(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, withoutEmptySlices(block)) {
+		t.Errorf("ReadBlockFrom() = %+v, want %+v", got, block)
+	}
+}
+
+// Copies the block with its empty slices set to nil, as gob decodes them
+func withoutEmptySlices(b *Block) *Block {
+	nilIfEmpty := func(s []byte) []byte {
+		if len(s) == 0 {
+			return nil
+		}
+		return s
+	}
+
+	c := *b
+	c.PrevBlockHash = nilIfEmpty(b.PrevBlockHash)
+	c.Hash = nilIfEmpty(b.Hash)
+	c.Transactions = nil
+	for _, tx := range b.Transactions {
+		txCopy := Transaction{ID: nilIfEmpty(tx.ID)}
+		for _, in := range tx.Vin {
+			txCopy.Vin = append(txCopy.Vin, TXInput{nilIfEmpty(in.Txid), in.Vout, in.ScriptSig})
+		}
+		txCopy.Vout = append(txCopy.Vout, tx.Vout...)
+		c.Transactions = append(c.Transactions, &txCopy)
+	}
+
+	return &c
+}
+
+func TestGenesisData(t *testing.T) {
+	first := newTestChain(t, WithGenesisData("first deployment"))
+	firstGenesis := first.GetBestBlockHash()
+	first.Db.Close()
+
+	second := newTestChain(t, WithGenesisData("second deployment"))
+	if bytes.Equal(second.GetBestBlockHash(), firstGenesis) {
+		t.Error("different genesis data gave the same genesis block")
+	}
+
+	// the data only applies when the chain is created
+	second = reopenTestChain(t, second, WithGenesisData("first deployment"))
+	genesis := tipBlock(t, second)
+	if data := genesis.Transactions[0].Vin[0].ScriptSig; !strings.HasSuffix(data, "second deployment") {
+		t.Errorf("genesis coinbase data = %q after reopening with other data", data)
+	}
+}
+
+func TestConfirmations(t *testing.T) {
+	bc := newTestChain(t)
+	tx := spendTestTX(t, bc, "alice", "bob", 4, 1)
+	mineTestBlock(t, bc, "carol", tx)
+
+	if got, err := bc.Confirmations(tx.ID); err != nil || got != 1 {
+		t.Errorf("Confirmations() = %d, %v in the tip block, want 1", got, err)
+	}
+
+	const buried = 3
+	for i := 0; i < buried; i++ {
+		mineTestBlock(t, bc, "carol")
+	}
+	if got, err := bc.Confirmations(tx.ID); err != nil || got != buried+1 {
+		t.Errorf("Confirmations() = %d, %v, want %d", got, err, buried+1)
+	}
+
+	if _, err := bc.Confirmations([]byte("unknown")); err != ErrTransactionNotFound {
+		t.Errorf("Confirmations() of an unknown transaction = %v, want ErrTransactionNotFound", err)
+	}
+}
+
+func TestProofOfWorkMaxNonce(t *testing.T) {
+	SetLogger(NopLogger)
+	block := newUnsealedBlock([]*Transaction{NewCoinbaseTX("alice", "", 0)}, nil, maxDifficulty)
+
+	pow := NewProofOfWork(block)
+	pow.MaxNonce = 1000
+
+	nonce, hash, err := pow.Run()
+	if err != ErrNonceExhausted {
+		t.Fatalf("Run() error = %v, want ErrNonceExhausted", err)
+	}
+	if nonce != 0 || hash != nil {
+		t.Errorf("Run() = %d, %x along with the error, want no result", nonce, hash)
+	}
+}
+
+// Regression test: an exhausted nonce search used to return the last hash
+// tried, which missed the target, and the block was stored as if valid
+func TestExhaustedSealStoresNothing(t *testing.T) {
+	SetLogger(NopLogger)
+	block := newUnsealedBlock([]*Transaction{NewCoinbaseTX("alice", "", 0)}, nil, maxDifficulty)
+	if err := (PoWConsensus{MaxNonce: 1000}).Seal(block); err != ErrNonceExhausted {
+		t.Fatalf("Seal() = %v, want ErrNonceExhausted", err)
+	}
+	if len(block.Hash) != 0 {
+		t.Errorf("Seal() left hash %x on a block it could not seal", block.Hash)
+	}
+
+	bc := newTestChain(t, WithDevMode())
+	if err := bc.SetDifficulty(maxDifficulty); err != nil {
+		t.Fatal(err)
+	}
+	bc = reopenTestChain(t, bc, WithDevMode(), WithConsensus(PoWConsensus{MaxNonce: 1000}))
+	tip := bc.GetBestBlockHash()
+
+	_, err := bc.MineBlock([]*Transaction{NewCoinbaseTX("bob", "", 1)})
+	if err != ErrNonceExhausted {
+		t.Errorf("MineBlock() = %v, want ErrNonceExhausted", err)
+	}
+	if count, _ := bc.GetBlockCount(); count != 1 || !bytes.Equal(bc.GetBestBlockHash(), tip) {
+		t.Errorf("chain has %d blocks after a failed seal, want only genesis", count)
+	}
+}
+
+func TestSecondOpenIsLocked(t *testing.T) {
+	newTestChain(t)
+
+	start := time.Now()
+	_, err := NewBlockchain("alice")
+	if err != ErrDatabaseLocked {
+		t.Fatalf("NewBlockchain() = %v while the chain is open, want ErrDatabaseLocked", err)
+	}
+	if waited := time.Since(start); waited > dbOpenTimeout+time.Second {
+		t.Errorf("NewBlockchain() took %s to give up", waited)
+	}
+}
+
+func TestConcurrentMiningMovesCoinbaseHeight(t *testing.T) {
+	bc := newTestChain(t)
+
+	// every miner builds its coinbase for height 1; all but one lose the
+	// race and have to mine again at a later height
+	const miners = 8
+	errs := make(chan error, miners)
+	for i := 0; i < miners; i++ {
+		go func() {
+			_, err := bc.MineBlock([]*Transaction{NewCoinbaseTX("bob", "", 1)})
+			errs <- err
+		}()
+	}
+	for i := 0; i < miners; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("MineBlock() = %v", err)
+		}
+	}
+
+	if count, _ := bc.GetBlockCount(); count != miners+1 {
+		t.Errorf("GetBlockCount() = %d, want %d", count, miners+1)
+	}
+}
+
+func FuzzBlockRoundTrip(f *testing.F) {
+	f.Add(int64(1700000000), []byte{}, []byte{0, 1, 2}, 42, 8, uint32(0x2000ff00), "Reward to alice", 10)
+	f.Add(int64(-1), []byte("prev"), []byte{}, -5, 0, uint32(0), "", 0)
+
+	f.Fuzz(func(t *testing.T, timestamp int64, prevHash, hash []byte, nonce, difficulty int, bits uint32, data string, value int) {
+		tx := Transaction{nil, []TXInput{{prevHash, nonce, data}}, []TXOutput{{value, data}}}
+		tx.SetID()
+		block := BlockFromFields(timestamp, []*Transaction{&tx}, prevHash, hash, nonce, difficulty, bits)
+
+		serialized, err := block.Serialize()
+		if err != nil {
+			return
+		}
+
+		got, err := DeseralizeBlock(serialized)
+		if err != nil {
+			t.Fatalf("DeseralizeBlock() = %v for a block that serialized", err)
+		}
+		if !reflect.DeepEqual(got, withoutEmptySlices(block)) {
+			t.Errorf("round trip = %+v, want %+v", got, block)
+		}
+	})
+}
+
+func TestCreateWithDifficulty(t *testing.T) {
+	bc := newTestChain(t)
+	block := mineTestBlock(t, bc, "bob")
+
+	if block.Difficulty != 8 {
+		t.Errorf("block mined at %d bits, want 8", block.Difficulty)
+	}
+	// mining 24 bits takes long enough to slow the tests down, so compare
+	// the work each target takes instead
+	easy := NewProofOfWork(block).Difficulty()
+	hard := NewProofOfWork(newUnsealedBlock(nil, nil, targetBits)).Difficulty()
+	if hard/easy != 1<<16 {
+		t.Errorf("a 24 bit target takes %v times the work of an 8 bit one, want %d", hard/easy, 1<<16)
+	}
+
+	bc = reopenTestChain(t, bc, WithDifficulty(12))
+	if got := bc.Difficulty(); got != 8 {
+		t.Errorf("Difficulty() = %d after reopening with another difficulty, want the stored 8", got)
+	}
+}
+
+func TestDifficultyDoublesPerBit(t *testing.T) {
+	difficulty := func(bits int) float64 {
+		return NewProofOfWork(newUnsealedBlock(nil, nil, bits)).Difficulty()
+	}
+
+	if got := difficulty(minDifficulty); got != 1 {
+		t.Errorf("Difficulty() = %v at the easiest target, want 1", got)
+	}
+	for bits := minDifficulty; bits < maxDifficulty; bits++ {
+		if lower, higher := difficulty(bits), difficulty(bits+1); higher != 2*lower {
+			t.Errorf("Difficulty() = %v at %d bits and %v at %d, want it doubled", lower, bits, higher, bits+1)
+		}
+	}
+}
+
+func TestTargetHex(t *testing.T) {
+	tests := []struct {
+		bits int
+		want string
+	}{
+		{1, "8" + strings.Repeat("0", 63)},
+		{8, "01" + strings.Repeat("0", 62)},
+		{24, "000001" + strings.Repeat("0", 58)},
+	}
+
+	for _, tt := range tests {
+		pow := NewProofOfWork(newUnsealedBlock(nil, nil, tt.bits))
+		if got := pow.TargetHex(); got != tt.want {
+			t.Errorf("TargetHex() = %s at %d bits, want %s", got, tt.bits, tt.want)
+		}
+	}
+}
+
+func TestCreateWithDifficultyOutOfRange(t *testing.T) {
+	chdirTemp(t)
+	SetLogger(NopLogger)
+
+	for _, bits := range []int{minDifficulty - 1, maxDifficulty + 1} {
+		if _, err := CreateBlockchain("alice", WithDifficulty(bits)); err != ErrDifficultyRange {
+			t.Errorf("CreateBlockchain() = %v at %d bits, want ErrDifficultyRange", err, bits)
+		}
+		if dbExists() {
+			t.Fatalf("refusing %d bits left a database behind", bits)
+		}
+	}
+}
+
+func TestMiningOutput(t *testing.T) {
+	var out bytes.Buffer
+	// flushed by the chain after each block
+	w := bufio.NewWriter(&out)
+	bc := newTestChain(t, WithMiningOutput(w))
+	block := mineTestBlock(t, bc, "bob")
+
+	want := fmt.Sprintf("Mining new block\n%x\n", block.Hash)
+	if got := out.String(); !strings.HasSuffix(got, want) {
+		t.Errorf("mining output = %q, want it to end with %q", got, want)
+	}
+}
+
+func TestSeededNonce(t *testing.T) {
+	bc := newTestChain(t, WithDevMode(), WithSeededNonce())
+
+	mine := func() *Block {
+		block := newUnsealedBlock([]*Transaction{NewCoinbaseTX("bob", "", 1)}, bc.GetBestBlockHash(), 8)
+		block.Timestamp = 1700000000
+		if err := bc.seal(block); err != nil {
+			t.Fatal(err)
+		}
+		return block
+	}
+
+	first, second := mine(), mine()
+	if first.Nonce != second.Nonce || !bytes.Equal(first.Hash, second.Hash) {
+		t.Errorf("mining the same block twice found nonces %d and %d", first.Nonce, second.Nonce)
+	}
+	if start := NewProofOfWork(first).seededStart(); first.Nonce < start {
+		t.Errorf("nonce %d found below the seeded start %d", first.Nonce, start)
+	}
+
+	chdirTemp(t)
+	if _, err := CreateBlockchain("alice", WithSeededNonce()); err != ErrDevModeRequired {
+		t.Errorf("CreateBlockchain() = %v with a seeded nonce outside DevMode, want ErrDevModeRequired", err)
+	}
+}
+
+func TestResetBlockchain(t *testing.T) {
+	bc := newTestChain(t, WithDevMode())
+	for i := 0; i < 4; i++ {
+		mineTestBlock(t, bc, "bob")
+	}
+	bc.Db.Close()
+
+	if _, err := ResetBlockchain("carol", WithDifficulty(8)); err != ErrDevModeRequired {
+		t.Fatalf("ResetBlockchain() = %v outside DevMode, want ErrDevModeRequired", err)
+	}
+
+	bc, err := ResetBlockchain("carol", WithDevMode(), WithDifficulty(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bc.Db.Close()
+
+	if count, _ := bc.GetBlockCount(); count != 1 {
+		t.Errorf("GetBlockCount() = %d after resetting a 5 block chain, want 1", count)
+	}
+	if got := balance(t, bc, "carol"); got != subsidy {
+		t.Errorf("balance of carol = %d, want the new genesis reward", got)
+	}
+	if got := balance(t, bc, "bob"); got != 0 {
+		t.Errorf("balance of bob = %d, want the old blocks gone", got)
+	}
+}
+
+func TestResetWithBadOptionKeepsChain(t *testing.T) {
+	bc := newTestChain(t, WithDevMode())
+	tip := mineTestBlock(t, bc, "bob")
+	bc.Db.Close()
+
+	if _, err := ResetBlockchain("carol", WithDevMode(), WithDifficulty(maxDifficulty+1)); err != ErrDifficultyRange {
+		t.Fatalf("ResetBlockchain() = %v with an out of range difficulty, want ErrDifficultyRange", err)
+	}
+
+	bc = reopenTestChain(t, bc)
+	if got := bc.GetBestBlockHash(); !bytes.Equal(got, tip.Hash) {
+		t.Errorf("tip = %x after a failed reset, want %x", got, tip.Hash)
+	}
+	if got := balance(t, bc, "bob"); got != subsidy {
+		t.Errorf("balance of bob = %d after a failed reset, want %d", got, subsidy)
+	}
+	if _, err := os.Stat(dbFile + ".reset"); !os.IsNotExist(err) {
+		t.Errorf("failed reset left %s.reset behind: %v", dbFile, err)
+	}
+}
@@ -0,0 +1,91 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+var ErrMalformedBlock = errors.New("malformed compact block encoding")
+
+// A compact binary encoding of the block: the header fields as varints and
+// length-prefixed bytes, followed by each transaction in its compact form
+// and finally Bits. Bits is left out when unset, as it was by encodings
+// from before the field existed.
+func (b *Block) MarshalCompact() []byte {
+	var buf []byte
+
+	buf = binary.AppendVarint(buf, b.Timestamp)
+	buf = appendBytes(buf, b.PrevBlockHash)
+	buf = appendBytes(buf, b.Hash)
+	buf = binary.AppendVarint(buf, int64(b.Nonce))
+	buf = binary.AppendVarint(buf, int64(b.Difficulty))
+
+	buf = binary.AppendUvarint(buf, uint64(len(b.Transactions)))
+	for _, tx := range b.Transactions {
+		buf = appendBytes(buf, tx.MarshalCompact())
+	}
+
+	if b.Bits != 0 {
+		buf = binary.AppendUvarint(buf, uint64(b.Bits))
+	}
+
+	return buf
+}
+
+// Decodes a block written by MarshalCompact
+func UnmarshalCompactBlock(data []byte) (*Block, error) {
+	r := bytes.NewReader(data)
+	block := &Block{}
+
+	timestamp, err := binary.ReadVarint(r)
+	if err != nil {
+		return nil, ErrMalformedBlock
+	}
+	block.Timestamp = timestamp
+
+	if block.PrevBlockHash, err = readBytes(r); err != nil {
+		return nil, ErrMalformedBlock
+	}
+	if block.Hash, err = readBytes(r); err != nil {
+		return nil, ErrMalformedBlock
+	}
+	if block.Nonce, err = readInt(r); err != nil {
+		return nil, ErrMalformedBlock
+	}
+	if block.Difficulty, err = readInt(r); err != nil {
+		return nil, ErrMalformedBlock
+	}
+
+	count, err := readCount(r)
+	if err != nil {
+		return nil, ErrMalformedBlock
+	}
+	for i := 0; i < count; i++ {
+		encoded, err := readBytes(r)
+		if err != nil {
+			return nil, ErrMalformedBlock
+		}
+
+		tx := &Transaction{}
+		if err := tx.UnmarshalCompact(encoded); err != nil {
+			return nil, err
+		}
+		block.Transactions = append(block.Transactions, tx)
+	}
+
+	if r.Len() != 0 {
+		bits, err := binary.ReadUvarint(r)
+		if err != nil || bits == 0 || bits > math.MaxUint32 {
+			return nil, ErrMalformedBlock
+		}
+		block.Bits = uint32(bits)
+	}
+
+	if r.Len() != 0 {
+		return nil, ErrMalformedBlock
+	}
+
+	return block, nil
+}
@@ -0,0 +1,66 @@
+package blockchain
+
+import "math/big"
+
+// Compact targets pack a 256-bit target into 32 bits, as Bitcoin's nBits
+// does: the top byte is the length of the target in bytes and the low three
+// bytes hold its most significant bytes. Bit 23 is a sign bit, so the
+// mantissa is kept below 0x800000.
+const compactSignBit = 0x00800000
+
+// The target a hash must stay below to start with bits zero bits
+func targetForBits(bits int) *big.Int {
+	return new(big.Int).Lsh(big.NewInt(1), uint(256-bits))
+}
+
+// Expands a compact target. Precision beyond the three mantissa bytes is
+// lost, so this is the exact inverse of TargetToCompact only for targets
+// that fit.
+func CompactToTarget(bits uint32) *big.Int {
+	exponent := uint(bits >> 24)
+	mantissa := int64(bits & (compactSignBit - 1))
+
+	target := big.NewInt(mantissa)
+	if exponent <= 3 {
+		target.Rsh(target, 8*(3-exponent))
+	} else {
+		target.Lsh(target, 8*(exponent-3))
+	}
+
+	if bits&compactSignBit != 0 {
+		target.Neg(target)
+	}
+
+	return target
+}
+
+// Packs a target into its compact form, truncating it to its three most
+// significant bytes
+func TargetToCompact(target *big.Int) uint32 {
+	if target.Sign() == 0 {
+		return 0
+	}
+
+	abs := new(big.Int).Abs(target)
+	size := uint((abs.BitLen() + 7) / 8)
+
+	var mantissa uint32
+	if size <= 3 {
+		mantissa = uint32(abs.Uint64() << (8 * (3 - size)))
+	} else {
+		mantissa = uint32(new(big.Int).Rsh(abs, 8*(size-3)).Uint64())
+	}
+
+	// keep the sign bit clear by moving to a longer exponent
+	if mantissa&compactSignBit != 0 {
+		mantissa >>= 8
+		size++
+	}
+
+	compact := uint32(size)<<24 | mantissa
+	if target.Sign() < 0 {
+		compact |= compactSignBit
+	}
+
+	return compact
+}
@@ -0,0 +1,69 @@
+package blockchain
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestCompactTargetRoundTrip(t *testing.T) {
+	targets := []*big.Int{
+		big.NewInt(0x7f),
+		big.NewInt(0x80),
+		big.NewInt(0x1234),
+		big.NewInt(0x123456),
+		// Bitcoin's genesis target, 0x1d00ffff
+		new(big.Int).Lsh(big.NewInt(0xffff), 8*(0x1d-3)),
+	}
+	for bits := minDifficulty; bits <= maxDifficulty; bits++ {
+		targets = append(targets, targetForBits(bits))
+	}
+
+	for _, target := range targets {
+		compact := TargetToCompact(target)
+		if compact&compactSignBit != 0 {
+			t.Errorf("TargetToCompact(%x) = %#08x has the sign bit set", target, compact)
+		}
+		if got := CompactToTarget(compact); got.Cmp(target) != 0 {
+			t.Errorf("CompactToTarget(TargetToCompact(%x)) = %x", target, got)
+		}
+	}
+}
+
+func TestCompactTargetKnownValues(t *testing.T) {
+	tests := []struct {
+		compact uint32
+		target  *big.Int
+	}{
+		{0x1d00ffff, new(big.Int).Lsh(big.NewInt(0xffff), 8*(0x1d-3))},
+		{0x03123456, big.NewInt(0x123456)},
+		{0x02008000, big.NewInt(0x80)},
+		{0x01003456, big.NewInt(0)},
+		{0x04923456, big.NewInt(-0x12345600)},
+	}
+
+	for _, tt := range tests {
+		if got := CompactToTarget(tt.compact); got.Cmp(tt.target) != 0 {
+			t.Errorf("CompactToTarget(%#08x) = %x, want %x", tt.compact, got, tt.target)
+		}
+	}
+
+	// precision below the three mantissa bytes is dropped
+	if got := TargetToCompact(big.NewInt(0x12345678)); got != 0x04123456 {
+		t.Errorf("TargetToCompact(0x12345678) = %#08x, want 0x04123456", got)
+	}
+}
+
+func TestBlocksWithoutBitsStillValidate(t *testing.T) {
+	SetLogger(NopLogger)
+
+	// blocks stored before Bits existed are sealed without it
+	block := newUnsealedBlock([]*Transaction{NewCoinbaseTX("alice", "", 0)}, nil, 8)
+	block.Bits = 0
+	if err := (PoWConsensus{}).Seal(block); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := block.Validate(nil); err != nil {
+		t.Errorf("Validate() = %v for a block without Bits", err)
+	}
+}
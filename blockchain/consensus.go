@@ -0,0 +1,145 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"strconv"
+)
+
+// Decides how blocks are sealed and which seals the chain accepts
+type Consensus interface {
+	// Fills in the block's Hash (and Nonce, if the scheme uses one)
+	Seal(block *Block) error
+	Verify(block *Block) bool
+}
+
+// Kind of consensus a chain was created with, kept in the meta bucket
+const consensusKey = "consensus"
+
+const (
+	consensusPoW = iota
+	consensusInstant
+	consensusCustom
+)
+
+var ErrConsensusMismatch = errors.New("chain was created with a different consensus")
+
+func consensusKind(c Consensus) int {
+	switch c.(type) {
+	case PoWConsensus:
+		return consensusPoW
+	case instantSeal:
+		return consensusInstant
+	default:
+		return consensusCustom
+	}
+}
+
+func consensusName(kind int) string {
+	switch kind {
+	case consensusPoW:
+		return "proof of work"
+	case consensusInstant:
+		return "instant seal"
+	default:
+		return "a custom consensus"
+	}
+}
+
+// Whether the chain's consensus accepts the block's seal
+func (bc *Blockchain) VerifySeal(block *Block) bool {
+	return bc.consensus.Verify(block)
+}
+
+// The default consensus: blocks are sealed by searching for a nonce that
+// meets the proof of work target
+type PoWConsensus struct {
+	// Limits the nonce search when non-zero; Seal then fails with
+	// ErrNonceExhausted if no nonce up to it works
+	MaxNonce int
+
+	// Starts the nonce search at a value derived from the block's other
+	// fields instead of 0, see WithSeededNonce
+	SeededStart bool
+
+	// Receives the mining messages instead of the package logger when set.
+	// It is flushed after each block if it has a Flush method.
+	Output io.Writer
+}
+
+func (c PoWConsensus) Seal(block *Block) error {
+	pow := NewProofOfWork(block)
+	if c.MaxNonce > 0 {
+		pow.MaxNonce = c.MaxNonce
+	}
+	if c.SeededStart {
+		pow.StartNonce = pow.seededStart() % pow.MaxNonce
+	}
+	if c.Output != nil {
+		pow.log = NewStdLogger(c.Output, LevelInfo)
+	}
+
+	nonce, hash, err := pow.Run()
+	if f, ok := c.Output.(interface{ Flush() error }); ok {
+		if err := f.Flush(); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	block.Hash = hash
+	block.Nonce = nonce
+
+	return nil
+}
+
+func (PoWConsensus) Verify(block *Block) bool {
+	return block.verifyPoW() == nil
+}
+
+// Seals a block with the chain's consensus, applying the proof of work
+// settings of WithMiningOutput and WithSeededNonce
+func (bc *Blockchain) seal(block *Block) error {
+	if c, ok := bc.consensus.(PoWConsensus); ok {
+		if bc.miningOutput != nil {
+			c.Output = bc.miningOutput
+		}
+		if bc.seededNonce {
+			c.SeededStart = true
+		}
+		return c.Seal(block)
+	}
+
+	return bc.consensus.Seal(block)
+}
+
+// Seals blocks with a plain hash of their contents and no nonce search.
+// It is only reachable through WithInstantSeal, which requires DevMode, so
+// it can't be mistaken for real consensus.
+type instantSeal struct{}
+
+func (instantSeal) Seal(block *Block) error {
+	block.Nonce = 0
+	block.Hash = instantSealHash(block)
+
+	return nil
+}
+
+func (instantSeal) Verify(block *Block) bool {
+	return block.Nonce == 0 && bytes.Equal(block.Hash, instantSealHash(block))
+}
+
+func instantSealHash(block *Block) []byte {
+	hash := sha256.Sum256(bytes.Join([][]byte{
+		[]byte("instantseal"),
+		block.PrevBlockHash,
+		block.HashTransactions(),
+		[]byte(strconv.FormatInt(block.Timestamp, 10)),
+	}, []byte{}))
+
+	return hash[:]
+}
@@ -0,0 +1,96 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Accepts every block, sealing it with a plain hash of its header
+type noopConsensus struct{}
+
+func (noopConsensus) Seal(block *Block) error {
+	hash := sha256.Sum256(append(append([]byte{}, block.PrevBlockHash...), block.HashTransactions()...))
+	block.Hash = hash[:]
+
+	return nil
+}
+
+func (noopConsensus) Verify(block *Block) bool {
+	return true
+}
+
+func TestCustomConsensus(t *testing.T) {
+	bc := newTestChain(t, WithConsensus(noopConsensus{}))
+
+	const added = 100
+	for i := 0; i < added; i++ {
+		mineTestBlock(t, bc, "bob")
+	}
+
+	if count, _ := bc.GetBlockCount(); count != added+1 {
+		t.Errorf("GetBlockCount() = %d, want %d", count, added+1)
+	}
+	if tip := tipBlock(t, bc); tip.Nonce != 0 {
+		t.Errorf("tip nonce = %d, want no nonce search", tip.Nonce)
+	}
+}
+
+func TestPoWConsensusVerifies(t *testing.T) {
+	bc := newTestChain(t)
+	block := mineTestBlock(t, bc, "bob")
+
+	if !bc.VerifySeal(block) {
+		t.Error("VerifySeal() = false for a mined block")
+	}
+
+	unsealed := newUnsealedBlock([]*Transaction{NewCoinbaseTX("bob", "", 2)}, block.Hash, 8)
+	noopConsensus{}.Seal(unsealed)
+	if bc.VerifySeal(unsealed) {
+		t.Error("VerifySeal() = true for a block without proof of work")
+	}
+	if err := bc.AddBlock(unsealed); err == nil {
+		t.Error("AddBlock() accepted a block without proof of work")
+	}
+}
+
+func TestInstantSealInDevMode(t *testing.T) {
+	bc := newTestChain(t, WithDevMode(), WithInstantSeal())
+	block := mineTestBlock(t, bc, "bob")
+
+	if block.Nonce != 0 || !bc.VerifySeal(block) {
+		t.Errorf("block nonce = %d, VerifySeal() = %v, want an instant seal", block.Nonce, bc.VerifySeal(block))
+	}
+	// an instant seal does not meet the proof of work target
+	if err := block.Validate(nil); err == nil {
+		t.Error("Validate() accepted an instant sealed block as proof of work")
+	}
+
+	bc.Db.Close()
+	_, err := NewBlockchain("alice")
+	if !errors.Is(err, ErrConsensusMismatch) {
+		t.Fatalf("NewBlockchain() = %v without instant seal, want ErrConsensusMismatch", err)
+	}
+	if !strings.Contains(err.Error(), "instant seal") {
+		t.Errorf("error %q does not name the chain's consensus", err)
+	}
+}
+
+func TestInstantSealRequiresDevMode(t *testing.T) {
+	chdirTemp(t)
+	SetLogger(NopLogger)
+
+	if _, err := CreateBlockchain("alice", WithInstantSeal()); err != ErrDevModeRequired {
+		t.Errorf("CreateBlockchain() = %v without DevMode, want ErrDevModeRequired", err)
+	}
+	if dbExists() {
+		t.Error("a refused chain left a database behind")
+	}
+
+	bc := newTestChain(t, WithDevMode(), WithInstantSeal())
+	bc.Db.Close()
+	if _, err := NewBlockchain("alice", WithInstantSeal()); err != ErrDevModeRequired {
+		t.Errorf("NewBlockchain() = %v without DevMode, want ErrDevModeRequired", err)
+	}
+}
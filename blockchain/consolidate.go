@@ -0,0 +1,38 @@
+package blockchain
+
+import (
+	"errors"
+)
+
+// Outputs worth less than this are consolidated by default
+const DefaultDustThreshold = 5
+
+// Fewest dust outputs worth consolidating by default
+const DefaultMinDustOutputs = 3
+
+var ErrNotEnoughDust = errors.New("address has too few dust outputs to consolidate")
+
+// Moves every spendable output of address worth less than threshold to a
+// single output paying address again, less sweepFee, and mines the
+// transaction. Nothing is done if there are fewer than minOutputs of them.
+// Larger outputs are left alone and the block's coinbase reward also goes
+// to address.
+func ConsolidateDust(address string, threshold, minOutputs int, bc *Blockchain) (*Transaction, error) {
+	outPoints, err := bc.SpendableOutputs(address)
+	if err != nil {
+		return nil, err
+	}
+
+	var dust []OutPoint
+	for _, out := range outPoints {
+		if out.Value < threshold {
+			dust = append(dust, out)
+		}
+	}
+
+	if len(dust) < minOutputs || totalValue(dust) <= sweepFee {
+		return nil, ErrNotEnoughDust
+	}
+
+	return spendOutputs(address, address, dust, bc)
+}
@@ -0,0 +1,43 @@
+package blockchain
+
+import "testing"
+
+func TestConsolidateDust(t *testing.T) {
+	bc := newTestChain(t, WithAllocations(map[string]int{"alice": 100}))
+	for i := 0; i < 5; i++ {
+		mineTestBlock(t, bc, "carol", spendTestTX(t, bc, "alice", "bob", 2, 1))
+	}
+	mineTestBlock(t, bc, "carol", spendTestTX(t, bc, "alice", "bob", 50, 1))
+
+	if _, err := ConsolidateDust("bob", 5, 6, bc); err != ErrNotEnoughDust {
+		t.Errorf("ConsolidateDust() = %v with 5 dust outputs and a minimum of 6, want ErrNotEnoughDust", err)
+	}
+
+	tx, err := ConsolidateDust("bob", 5, 3, bc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tx.Vin) != 5 {
+		t.Errorf("consolidation spent %d outputs, want the 5 dust outputs", len(tx.Vin))
+	}
+
+	outPoints, err := bc.UTXOsForAddress("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make(map[int]int)
+	for _, out := range outPoints {
+		values[out.Value]++
+	}
+	// the untouched large output, the consolidated dust less the fee and
+	// the reward for the block carrying the consolidation
+	want := map[int]int{50: 1, 5*2 - sweepFee: 1, subsidy: 1}
+	if len(values) != len(want) {
+		t.Fatalf("bob's outputs %+v after consolidating, want values %v", outPoints, want)
+	}
+	for value, count := range want {
+		if values[value] != count {
+			t.Errorf("bob has %d outputs of %d after consolidating, want %d", values[value], value, count)
+		}
+	}
+}
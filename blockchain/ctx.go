@@ -0,0 +1,157 @@
+package blockchain
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Runs f in its own goroutine and waits for it or for ctx to be done,
+// whichever comes first. f is not started if ctx is already done. A bolt
+// transaction can't be interrupted, so when ctx wins f carries on in the
+// background and its result is dropped; a write may still be committed.
+func runWithContext(ctx context.Context, f func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- f()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// States of a write run by runWriteWithContext
+const (
+	writeRunning int32 = iota
+	writeCommitting
+	writeAbandoned
+)
+
+// Like runWithContext, for a write that must not land once its caller has
+// been told it failed. f calls commit just before its bolt transaction
+// would commit and rolls back if it returns an error. Whichever of commit
+// and ctx comes first wins: once ctx is done commit fails, and once commit
+// has succeeded the result of f is waited for even if ctx is done.
+func runWriteWithContext(ctx context.Context, f func(commit func() error) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var state int32
+	commit := func() error {
+		if !atomic.CompareAndSwapInt32(&state, writeRunning, writeCommitting) {
+			return ctx.Err()
+		}
+
+		return nil
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- f(commit)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		if atomic.CompareAndSwapInt32(&state, writeRunning, writeAbandoned) {
+			return ctx.Err()
+		}
+
+		return <-done
+	}
+}
+
+// AddBlock that returns ctx.Err() if ctx is done before the block is
+// stored, in which case the block is not added
+func (bc *Blockchain) AddBlockContext(ctx context.Context, block *Block) error {
+	return runWriteWithContext(ctx, func(commit func() error) error {
+		return bc.addBlock(block, commit)
+	})
+}
+
+// GetBlock that returns ctx.Err() if ctx is done before the block is read
+func (bc *Blockchain) GetBlockContext(ctx context.Context, blockHash []byte) (*Block, error) {
+	var block *Block
+	err := runWithContext(ctx, func() error {
+		var err error
+		block, err = bc.GetBlock(blockHash)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return block, nil
+}
+
+// GetOutput that returns ctx.Err() if ctx is done before the output is found
+func (bc *Blockchain) GetOutputContext(ctx context.Context, txid []byte, vout int) (TXOutput, error) {
+	var out TXOutput
+	err := runWithContext(ctx, func() error {
+		var err error
+		out, err = bc.GetOutput(txid, vout)
+		return err
+	})
+	if err != nil {
+		return TXOutput{}, err
+	}
+
+	return out, nil
+}
+
+// UTXOsForAddress that returns ctx.Err() if ctx is done before the chain
+// has been scanned
+func (bc *Blockchain) UTXOsForAddressContext(ctx context.Context, address string) ([]OutPoint, error) {
+	var outPoints []OutPoint
+	err := runWithContext(ctx, func() error {
+		var err error
+		outPoints, err = bc.UTXOsForAddress(address)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return outPoints, nil
+}
+
+// SpendableOutputs that returns ctx.Err() if ctx is done before the chain
+// has been scanned
+func (bc *Blockchain) SpendableOutputsContext(ctx context.Context, address string) ([]OutPoint, error) {
+	var outPoints []OutPoint
+	err := runWithContext(ctx, func() error {
+		var err error
+		outPoints, err = bc.SpendableOutputs(address)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return outPoints, nil
+}
+
+// UnspentOutputs that returns ctx.Err() if ctx is done before the chain has
+// been scanned
+func (bc *Blockchain) UnspentOutputsContext(ctx context.Context) ([]UnspentOutput, error) {
+	var outputs []UnspentOutput
+	err := runWithContext(ctx, func() error {
+		var err error
+		outputs, err = bc.UnspentOutputs()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return outputs, nil
+}
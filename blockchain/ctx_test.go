@@ -0,0 +1,72 @@
+package blockchain
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestContextDeadlineReturnsPromptly(t *testing.T) {
+	bc := newTestChain(t)
+	block := newUnsealedBlock([]*Transaction{NewCoinbaseTX("bob", "", 1)}, bc.GetBestBlockHash(), bc.Difficulty())
+	block.Timestamp = tipBlock(t, bc).Timestamp + 1
+	if err := bc.seal(block); err != nil {
+		t.Fatal(err)
+	}
+
+	// an open write transaction holds up AddBlock until it is rolled back
+	tx, err := bc.Db.Begin(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = bc.AddBlockContext(ctx, block)
+	if err != context.DeadlineExceeded {
+		t.Errorf("AddBlockContext() = %v with the database busy, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("AddBlockContext() took %s to time out", elapsed)
+	}
+
+	// AddBlock holds the chain lock while it waits for the database, so
+	// once GetBlockCount gets it the abandoned write has finished
+	tx.Rollback()
+	if count, _ := bc.GetBlockCount(); count != 1 {
+		t.Errorf("GetBlockCount() = %d after a timed out AddBlockContext, want 1", count)
+	}
+	if !bytes.Equal(bc.GetBestBlockHash(), block.PrevBlockHash) {
+		t.Error("tip moved to a block whose AddBlockContext timed out")
+	}
+	if _, err := bc.GetBlock(block.Hash); err == nil {
+		t.Error("block whose AddBlockContext timed out is stored")
+	}
+
+	if err := bc.AddBlockContext(context.Background(), block); err != nil {
+		t.Errorf("AddBlockContext() = %v adding the block again with a live context", err)
+	}
+	if !bytes.Equal(bc.GetBestBlockHash(), block.Hash) {
+		t.Error("tip did not move to the added block")
+	}
+}
+
+func TestContextDoneBeforeStart(t *testing.T) {
+	bc := newTestChain(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := bc.GetBlockContext(ctx, bc.GetBestBlockHash()); err != context.Canceled {
+		t.Errorf("GetBlockContext() = %v with a cancelled context, want context.Canceled", err)
+	}
+	if _, err := bc.UTXOsForAddressContext(ctx, "alice"); err != context.Canceled {
+		t.Errorf("UTXOsForAddressContext() = %v with a cancelled context, want context.Canceled", err)
+	}
+
+	block, err := bc.GetBlockContext(context.Background(), bc.GetBestBlockHash())
+	if err != nil || block == nil {
+		t.Errorf("GetBlockContext() = %v, %v with a live context", block, err)
+	}
+}
@@ -0,0 +1,43 @@
+package blockchain
+
+import (
+	"fmt"
+
+	"github.com/boltdb/bolt"
+)
+
+// A raw key and value from the database
+type BucketEntry struct {
+	Key   []byte
+	Value []byte
+}
+
+// Lists every key and value of the named bucket in key order. The database
+// is opened read-only and nothing about the chain is checked, so this works
+// on a database NewBlockchain would refuse to open.
+func DumpBucket(name string) ([]BucketEntry, error) {
+	db, err := bolt.Open(dbFile, 0600, &bolt.Options{Timeout: dbOpenTimeout, ReadOnly: true})
+	if err == bolt.ErrTimeout {
+		return nil, ErrDatabaseLocked
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var entries []BucketEntry
+	err = db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(name))
+		if b == nil {
+			return fmt.Errorf("no bucket named '%s'", name)
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			// copied because they are only valid for the life of the transaction
+			entries = append(entries, BucketEntry{append([]byte{}, k...), append([]byte{}, v...)})
+			return nil
+		})
+	})
+
+	return entries, err
+}
@@ -0,0 +1,103 @@
+package blockchain
+
+import (
+	"errors"
+	"sort"
+)
+
+// Number of recent blocks sampled when estimating fee rates
+const feeEstimateBlocks = 20
+
+// Fee rates are paid per feeRateBytes serialized bytes, since a fee of a
+// few units spread over each byte of a transaction rounds down to nothing
+const feeRateBytes = 1000
+
+// Fee rate returned when recent blocks carry no fee-paying transactions,
+// the lowest rate a fee-paying sample can have
+const defaultFeeRate = 1
+
+// Returns the fee paid by a transaction, resolving each of its inputs
+// against the chain. Coinbase transactions pay no fee.
+func (bc *Blockchain) TransactionFee(tx *Transaction) (int, error) {
+	if tx.IsCoinbase() {
+		return 0, nil
+	}
+
+	inputs := 0
+	for _, in := range tx.Vin {
+		prevOut, err := bc.GetOutput(in.Txid, in.Vout)
+		if err != nil {
+			return 0, err
+		}
+		inputs += prevOut.Value
+	}
+
+	outputs := 0
+	for _, out := range tx.Vout {
+		outputs += out.Value
+	}
+
+	return inputs - outputs, nil
+}
+
+// Returns the fee paid per feeRateBytes serialized bytes of the transaction
+func (bc *Blockchain) FeePerKB(tx *Transaction) (int, error) {
+	fee, err := bc.TransactionFee(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	return fee * feeRateBytes / tx.SerializedSize(), nil
+}
+
+// Estimates the fee per feeRateBytes bytes needed for a transaction to
+// confirm within the given number of blocks. It samples the fee rates paid
+// in recent blocks and picks a higher percentile the sooner the
+// confirmation is wanted, from the 90th for the next block down to the
+// 10th. Transactions paying no fee are left out of the sample.
+func (bc *Blockchain) EstimateFeeRate(withinBlocks int) (int, error) {
+	if withinBlocks < 1 {
+		return 0, errors.New("fee estimate target must be at least one block")
+	}
+
+	var rates []int
+	bci := bc.Iterator()
+
+	for i := 0; i < feeEstimateBlocks; i++ {
+		block, err := bci.Next()
+		if err != nil {
+			return 0, err
+		}
+
+		for _, tx := range block.Transactions {
+			if tx.IsCoinbase() {
+				continue
+			}
+
+			rate, err := bc.FeePerKB(tx)
+			if err != nil {
+				return 0, err
+			}
+			if rate > 0 {
+				rates = append(rates, rate)
+			}
+		}
+
+		if len(block.PrevBlockHash) == 0 {
+			break
+		}
+	}
+
+	if len(rates) == 0 {
+		return defaultFeeRate, nil
+	}
+
+	sort.Ints(rates)
+
+	percentile := 90 - 10*(withinBlocks-1)
+	if percentile < 10 {
+		percentile = 10
+	}
+
+	return rates[(len(rates)-1)*percentile/100], nil
+}
@@ -0,0 +1,77 @@
+package blockchain
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestSizeAndFeePerKB(t *testing.T) {
+	bc := newTestChain(t, WithAllocations(map[string]int{"alice": 100000}))
+	tx := spendTestTX(t, bc, "alice", "bob", 10, 5000)
+
+	size := tx.SerializedSize()
+	if size != len(tx.Serialize()) {
+		t.Errorf("SerializedSize() = %d, want %d", size, len(tx.Serialize()))
+	}
+
+	if fee, err := bc.TransactionFee(tx); err != nil || fee != 5000 {
+		t.Errorf("TransactionFee() = %d, %v, want 5000", fee, err)
+	}
+	if rate, err := bc.FeePerKB(tx); err != nil || rate != 5000*1000/size {
+		t.Errorf("FeePerKB() = %d, %v, want %d", rate, err, 5000*1000/size)
+	}
+
+	// a fee of 1 is well under a unit per byte but still a rate
+	small := spendTestTX(t, bc, "alice", "bob", 10, 1)
+	if rate, err := bc.FeePerKB(small); err != nil || rate == 0 {
+		t.Errorf("FeePerKB() = %d, %v for a fee of 1 on %d bytes, want it above 0", rate, err, small.SerializedSize())
+	}
+
+	genesis := tipBlock(t, bc)
+	if rate, err := bc.FeePerKB(genesis.Transactions[0]); err != nil || rate != 0 {
+		t.Errorf("FeePerKB() = %d, %v for a coinbase, want 0", rate, err)
+	}
+}
+
+func TestEstimateFeeRate(t *testing.T) {
+	bc := newTestChain(t, WithAllocations(map[string]int{"alice": 100000}))
+
+	if rate, err := bc.EstimateFeeRate(1); err != nil || rate != defaultFeeRate {
+		t.Errorf("EstimateFeeRate() = %d, %v on a chain without fees, want %d", rate, err, defaultFeeRate)
+	}
+
+	// ten transactions paying 1 to 10, and three paying nothing that must
+	// not pull the low percentiles down to 0
+	var rates []int
+	for fee := 1; fee <= 10; fee++ {
+		tx := spendTestTX(t, bc, "alice", "bob", 10, fee)
+		mineTestBlock(t, bc, "carol", tx)
+		rates = append(rates, fee*1000/tx.SerializedSize())
+	}
+	for i := 0; i < 3; i++ {
+		mineTestBlock(t, bc, "carol", spendTestTX(t, bc, "alice", "bob", 10, 0))
+	}
+	sort.Ints(rates)
+
+	tests := []struct {
+		withinBlocks int
+		want         int
+	}{
+		{1, rates[8]},
+		{5, rates[4]},
+		{9, rates[0]},
+		{20, rates[0]},
+	}
+	for _, tt := range tests {
+		if rate, err := bc.EstimateFeeRate(tt.withinBlocks); err != nil || rate != tt.want {
+			t.Errorf("EstimateFeeRate(%d) = %d, %v, want %d", tt.withinBlocks, rate, err, tt.want)
+		}
+	}
+	if rates[0] < defaultFeeRate {
+		t.Errorf("lowest sampled rate %d is below the default for a chain without fees", rates[0])
+	}
+
+	if _, err := bc.EstimateFeeRate(0); err == nil {
+		t.Error("EstimateFeeRate(0) succeeded, want an error")
+	}
+}
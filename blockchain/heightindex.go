@@ -0,0 +1,144 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+
+	"github.com/boltdb/bolt"
+)
+
+// Maps each height on the main chain to the hash of the block at that height
+const heightIndexBucket = "heights"
+
+var ErrHeightConflict = errors.New("a different block is already indexed at this height")
+
+var ErrHeightOutOfRange = errors.New("no block at this height on the main chain")
+
+func heightKey(height int) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(height))
+
+	return key
+}
+
+// Records the block at height, refusing to replace a different block
+// already indexed there. Only a reorg may rewrite existing heights, and it
+// does so through rebuildHeightIndex.
+func indexHeight(tx *bolt.Tx, height int, blockHash []byte) error {
+	b, err := tx.CreateBucketIfNotExists([]byte(heightIndexBucket))
+	if err != nil {
+		return err
+	}
+
+	key := heightKey(height)
+	if existing := b.Get(key); existing != nil && !bytes.Equal(existing, blockHash) {
+		return ErrHeightConflict
+	}
+
+	return b.Put(key, blockHash)
+}
+
+// Makes the height index describe the chain ending at tip, rewriting it
+// when it doesn't already
+func syncHeightIndex(tx *bolt.Tx, tip []byte, tipHeight int) error {
+	if b := tx.Bucket([]byte(heightIndexBucket)); b != nil {
+		if bytes.Equal(b.Get(heightKey(tipHeight)), tip) && b.Get(heightKey(tipHeight+1)) == nil {
+			return nil
+		}
+	}
+
+	return rebuildHeightIndex(tx, tip, tipHeight)
+}
+
+func rebuildHeightIndex(tx *bolt.Tx, tip []byte, tipHeight int) error {
+	if tx.Bucket([]byte(heightIndexBucket)) != nil {
+		if err := tx.DeleteBucket([]byte(heightIndexBucket)); err != nil {
+			return err
+		}
+	}
+
+	index, err := tx.CreateBucket([]byte(heightIndexBucket))
+	if err != nil {
+		return err
+	}
+
+	blocks := tx.Bucket([]byte(blocksBucket))
+	hash := tip
+
+	for height := tipHeight; height >= 0; height-- {
+		block, err := decodeBlockRecord(blocks.Get(hash))
+		if err != nil {
+			return err
+		}
+
+		if err := index.Put(heightKey(height), block.Hash); err != nil {
+			return err
+		}
+
+		hash = block.PrevBlockHash
+	}
+
+	return nil
+}
+
+// Returns the hash of the main chain block at height, genesis being 0
+func (bc *Blockchain) GetBlockHash(height int) ([]byte, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	if height < 0 || height > bc.height {
+		return nil, ErrHeightOutOfRange
+	}
+
+	var hash []byte
+	err := bc.Db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(heightIndexBucket))
+		if b == nil {
+			return ErrBlockNotFound
+		}
+
+		indexed := b.Get(heightKey(height))
+		if indexed == nil {
+			return ErrBlockNotFound
+		}
+		hash = append([]byte{}, indexed...)
+
+		return nil
+	})
+
+	return hash, err
+}
+
+// A single hash over every block hash from genesis to the tip. Two chains
+// have the same fingerprint only if they hold the same blocks, so nodes can
+// compare fingerprints instead of exchanging all the hashes.
+func (bc *Blockchain) Fingerprint() ([]byte, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	h := sha256.New()
+
+	err := bc.Db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(heightIndexBucket))
+		if b == nil {
+			return ErrBlockNotFound
+		}
+
+		for height := 0; height <= bc.height; height++ {
+			hash := b.Get(heightKey(height))
+			if hash == nil {
+				return ErrBlockNotFound
+			}
+			h.Write(hash)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return h.Sum(nil), nil
+}
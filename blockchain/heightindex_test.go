@@ -0,0 +1,106 @@
+package blockchain
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+// AddBlock can't trigger the conflict: it only takes blocks extending the
+// tip, and nothing is indexed above the tip since NewBlockchain syncs the
+// index with it. So the guard is exercised on the index directly.
+func TestIndexHeightRefusesConflict(t *testing.T) {
+	bc := newTestChain(t)
+	genesis := bc.GetBestBlockHash()
+
+	err := bc.Db.Update(func(tx *bolt.Tx) error {
+		if err := indexHeight(tx, 1, []byte("first block")); err != nil {
+			t.Fatalf("indexHeight() = %v for a free height", err)
+		}
+		if err := indexHeight(tx, 1, []byte("first block")); err != nil {
+			t.Errorf("indexHeight() = %v indexing the same block again", err)
+		}
+		if err := indexHeight(tx, 1, []byte("second block")); err != ErrHeightConflict {
+			t.Errorf("indexHeight() = %v for a taken height, want ErrHeightConflict", err)
+		}
+		if err := indexHeight(tx, 0, []byte("other genesis")); err != ErrHeightConflict {
+			t.Errorf("indexHeight() = %v replacing genesis, want ErrHeightConflict", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hash, err := bc.GetBlockHash(0); err != nil || !bytes.Equal(hash, genesis) {
+		t.Errorf("GetBlockHash(0) = %x, %v after a refused conflict, want %x", hash, err, genesis)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	bc := newTestChain(t)
+	mineTestBlock(t, bc, "bob")
+	mineTestBlock(t, bc, "bob")
+	bc.Db.Close()
+
+	// an identical chain on another node
+	data, err := os.ReadFile(dbFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copyPath := filepath.Join(t.TempDir(), dbFile)
+	if err := os.WriteFile(copyPath, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	bc = reopenTestChain(t, bc)
+	want, err := bc.Fingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc.Db.Close()
+
+	if err := os.Chdir(filepath.Dir(copyPath)); err != nil {
+		t.Fatal(err)
+	}
+	other := reopenTestChain(t, bc)
+	if got, err := other.Fingerprint(); err != nil || !bytes.Equal(got, want) {
+		t.Errorf("Fingerprint() = %x, %v for an identical chain, want %x", got, err, want)
+	}
+
+	mineTestBlock(t, other, "bob")
+	if got, _ := other.Fingerprint(); bytes.Equal(got, want) {
+		t.Error("a chain with an extra block has the same fingerprint")
+	}
+}
+
+func TestGetBlockHash(t *testing.T) {
+	bc := newTestChain(t)
+	for i := 0; i < 4; i++ {
+		mineTestBlock(t, bc, "bob")
+	}
+
+	var byHeight [][]byte
+	err := bc.walkChain(func(block *Block, depth int) error {
+		byHeight = append([][]byte{block.Hash}, byHeight...)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for height, want := range byHeight {
+		if got, err := bc.GetBlockHash(height); err != nil || !bytes.Equal(got, want) {
+			t.Errorf("GetBlockHash(%d) = %x, %v, want %x", height, got, err, want)
+		}
+	}
+	for _, height := range []int{-1, len(byHeight)} {
+		if _, err := bc.GetBlockHash(height); err != ErrHeightOutOfRange {
+			t.Errorf("GetBlockHash(%d) = %v, want ErrHeightOutOfRange", height, err)
+		}
+	}
+}
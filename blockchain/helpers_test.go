@@ -0,0 +1,140 @@
+package blockchain
+
+import (
+	"os"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+// Runs the rest of the test in an empty directory, since dbFile is a
+// relative path
+func chdirTemp(t testing.TB) {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Chdir(wd)
+	})
+}
+
+// Creates a chain in an empty directory whose genesis block pays alice.
+// Blocks are mined at 8 target bits so they only take a few hundred hashes.
+func newTestChain(t testing.TB, opts ...Option) *Blockchain {
+	t.Helper()
+
+	chdirTemp(t)
+	SetLogger(NopLogger)
+
+	bc, err := CreateBlockchain("alice", append([]Option{WithDifficulty(8)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		bc.Db.Close()
+	})
+
+	return bc
+}
+
+// Closes the chain and opens it again with opts
+func reopenTestChain(t testing.TB, bc *Blockchain, opts ...Option) *Blockchain {
+	t.Helper()
+
+	if err := bc.Db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := NewBlockchain("alice", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		reopened.Db.Close()
+	})
+
+	return reopened
+}
+
+// Mines a block holding txs and a coinbase paying miner, and returns it
+func mineTestBlock(t testing.TB, bc *Blockchain, miner string, txs ...*Transaction) *Block {
+	t.Helper()
+
+	count, err := bc.GetBlockCount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cbtx := NewCoinbaseTX(miner, "", count)
+
+	if _, err := bc.MineBlock(append([]*Transaction{cbtx}, txs...)); err != nil {
+		t.Fatal(err)
+	}
+
+	return tipBlock(t, bc)
+}
+
+func tipBlock(t testing.TB, bc *Blockchain) *Block {
+	t.Helper()
+
+	block, err := bc.GetBlock(bc.GetBestBlockHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return block
+}
+
+// Builds a transaction spending every unspent output of from, paying amount
+// to to and the rest, less fee, back to from
+func spendTestTX(t testing.TB, bc *Blockchain, from, to string, amount, fee int) *Transaction {
+	t.Helper()
+
+	outPoints, err := bc.UTXOsForAddress(from)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var inputs []TXInput
+	for _, out := range outPoints {
+		inputs = append(inputs, TXInput{out.Txid, out.Index, from})
+	}
+
+	outputs := []TXOutput{{amount, to}}
+	if change := totalValue(outPoints) - amount - fee; change > 0 {
+		outputs = append(outputs, TXOutput{change, from})
+	}
+
+	tx := Transaction{nil, inputs, outputs}
+	tx.SetID()
+
+	return &tx
+}
+
+func balance(t testing.TB, bc *Blockchain, address string) int {
+	t.Helper()
+
+	outPoints, err := bc.UTXOsForAddress(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return totalValue(outPoints)
+}
+
+// Points the "l" key at hash without going through the chain
+func setTip(t testing.TB, bc *Blockchain, hash []byte) {
+	t.Helper()
+
+	err := bc.Db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(blocksBucket)).Put([]byte("l"), hash)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
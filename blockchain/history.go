@@ -0,0 +1,58 @@
+package blockchain
+
+// A transaction that paid to or spent from an address
+type HistoryEntry struct {
+	Txid          []byte
+	Timestamp     int64
+	Height        int
+	Confirmations int
+
+	// Net change to the address's balance: positive for money received,
+	// negative for money sent including the fee
+	Amount int
+}
+
+// Scans the whole chain for the transactions paying to or spending from
+// address, oldest first
+func (bc *Blockchain) TransactionsForAddress(address string) ([]HistoryEntry, error) {
+	count, err := bc.GetBlockCount()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []HistoryEntry
+
+	err = bc.walkChain(func(block *Block, depth int) error {
+		var blockEntries []HistoryEntry
+
+		for _, tx := range block.Transactions {
+			outputs, inputs := addressActivity(tx, address)
+			if len(outputs) == 0 && len(inputs) == 0 {
+				continue
+			}
+
+			amount := 0
+			for _, outIdx := range outputs {
+				amount += tx.Vout[outIdx].Value
+			}
+			for _, in := range inputs {
+				prevOut, err := bc.GetOutput(in.Txid, in.Vout)
+				if err != nil {
+					return err
+				}
+				amount -= prevOut.Value
+			}
+
+			blockEntries = append(blockEntries, HistoryEntry{tx.ID, block.Timestamp, count - 1 - depth, depth + 1, amount})
+		}
+		// blocks are visited from the tip back
+		entries = append(blockEntries, entries...)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,72 @@
+package blockchain
+
+import (
+	"io"
+	"log"
+	"os"
+)
+
+// Receives the package's progress and error messages
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+type LogLevel int
+
+const (
+	LevelDebug LogLevel = iota
+	LevelInfo
+	LevelError
+)
+
+// Discards everything logged to it
+var NopLogger Logger = nopLogger{}
+
+var logger Logger = NewStdLogger(os.Stdout, LevelInfo)
+
+// Replaces the logger used by the package, e.g. with NopLogger to silence mining output
+func SetLogger(l Logger) {
+	if l == nil {
+		l = NopLogger
+	}
+	logger = l
+}
+
+type stdLogger struct {
+	log   *log.Logger
+	level LogLevel
+}
+
+// Returns a Logger backed by the standard library that writes messages at
+// or above level to w
+func NewStdLogger(w io.Writer, level LogLevel) Logger {
+	return &stdLogger{log.New(w, "", 0), level}
+}
+
+func (l *stdLogger) Debugf(format string, args ...interface{}) {
+	if l.level <= LevelDebug {
+		l.log.Printf(format, args...)
+	}
+}
+
+func (l *stdLogger) Infof(format string, args ...interface{}) {
+	if l.level <= LevelInfo {
+		l.log.Printf(format, args...)
+	}
+}
+
+func (l *stdLogger) Errorf(format string, args ...interface{}) {
+	if l.level <= LevelError {
+		l.log.Printf(format, args...)
+	}
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debugf(format string, args ...interface{}) {}
+
+func (nopLogger) Infof(format string, args ...interface{}) {}
+
+func (nopLogger) Errorf(format string, args ...interface{}) {}
@@ -0,0 +1,68 @@
+package blockchain
+
+import (
+	"fmt"
+	"testing"
+)
+
+type logEntry struct {
+	level   LogLevel
+	message string
+}
+
+// Logger that keeps everything logged to it
+type captureLogger struct {
+	entries []logEntry
+}
+
+func (l *captureLogger) Debugf(format string, args ...interface{}) {
+	l.entries = append(l.entries, logEntry{LevelDebug, fmt.Sprintf(format, args...)})
+}
+
+func (l *captureLogger) Infof(format string, args ...interface{}) {
+	l.entries = append(l.entries, logEntry{LevelInfo, fmt.Sprintf(format, args...)})
+}
+
+func (l *captureLogger) Errorf(format string, args ...interface{}) {
+	l.entries = append(l.entries, logEntry{LevelError, fmt.Sprintf(format, args...)})
+}
+
+func (l *captureLogger) logged(level LogLevel, message string) bool {
+	for _, entry := range l.entries {
+		if entry == (logEntry{level, message}) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestMiningLogsThroughLogger(t *testing.T) {
+	bc := newTestChain(t)
+	capture := &captureLogger{}
+	SetLogger(capture)
+	t.Cleanup(func() {
+		SetLogger(NopLogger)
+	})
+
+	block := mineTestBlock(t, bc, "bob")
+	if !capture.logged(LevelInfo, "Mining new block") {
+		t.Errorf("\"Mining new block\" not logged at Info, got %+v", capture.entries)
+	}
+	if hash := fmt.Sprintf("%x", block.Hash); !capture.logged(LevelInfo, hash) {
+		t.Errorf("block hash %s not logged at Info, got %+v", hash, capture.entries)
+	}
+
+	// a fixed block none of whose first MaxNonce nonces meet the hardest
+	// target, so the search runs long enough to report progress
+	unsealed := newUnsealedBlock([]*Transaction{NewCoinbaseTX("bob", "", 2)}, nil, maxDifficulty)
+	unsealed.Timestamp = 1700000000
+	pow := NewProofOfWork(unsealed)
+	pow.MaxNonce = 100000
+	if _, _, err := pow.Run(); err != ErrNonceExhausted {
+		t.Fatalf("Run() = %v, want ErrNonceExhausted", err)
+	}
+	if !capture.logged(LevelDebug, "Tried 100000 nonces") {
+		t.Errorf("nonce progress not logged at Debug, got %+v", capture.entries)
+	}
+}
@@ -0,0 +1,91 @@
+package blockchain
+
+import (
+	"math"
+)
+
+// Block interval, in seconds, the difficulty adjustment aims for
+const targetBlockTime = 60
+
+// Number of recent solve times the LWMA adjustment averages over
+const lwmaWindow = 60
+
+// Computes the difficulty for the next block with a linearly weighted
+// moving average of recent solve times: the most recent block counts the
+// most, the oldest in the window the least. Timestamps that go backwards
+// are treated as one second after the previous block and solve times are
+// capped at six target intervals, which limits how far a miner can skew the
+// result with timestamps. The result moves at most one bit from the tip's
+// difficulty and stays within the range SetDifficulty accepts.
+func (bc *Blockchain) NextDifficultyLWMA() (int, error) {
+	var blocks []*Block
+	bci := bc.Iterator()
+
+	for len(blocks) < lwmaWindow+1 {
+		block, err := bci.Next()
+		if err != nil {
+			return 0, err
+		}
+		blocks = append(blocks, block)
+
+		if len(block.PrevBlockHash) == 0 {
+			break
+		}
+	}
+
+	tipBits := blockDifficulty(blocks[0])
+	if len(blocks) < 2 {
+		return tipBits, nil
+	}
+
+	// oldest first
+	for i, j := 0, len(blocks)-1; i < j; i, j = i+1, j-1 {
+		blocks[i], blocks[j] = blocks[j], blocks[i]
+	}
+
+	n := len(blocks) - 1
+	weightedSolveTimes := 0.0
+	sumDifficulty := 0.0
+	previous := blocks[0].Timestamp
+
+	for i := 1; i <= n; i++ {
+		timestamp := blocks[i].Timestamp
+		if timestamp <= previous {
+			timestamp = previous + 1
+		}
+
+		solveTime := math.Min(float64(timestamp-previous), 6*targetBlockTime)
+		previous = timestamp
+
+		weightedSolveTimes += solveTime * float64(i)
+		sumDifficulty += math.Exp2(float64(blockDifficulty(blocks[i])))
+	}
+
+	weights := float64(n*(n+1)) / 2
+	next := sumDifficulty / float64(n) * targetBlockTime * weights / weightedSolveTimes
+	bits := int(math.Round(math.Log2(next)))
+
+	if bits > tipBits+1 {
+		bits = tipBits + 1
+	}
+	if bits < tipBits-1 {
+		bits = tipBits - 1
+	}
+	if bits < minDifficulty {
+		bits = minDifficulty
+	}
+	if bits > maxDifficulty {
+		bits = maxDifficulty
+	}
+
+	return bits, nil
+}
+
+// Target bits a block was sealed at, counting legacy blocks as targetBits
+func blockDifficulty(block *Block) int {
+	if block.Difficulty == 0 {
+		return targetBits
+	}
+
+	return block.Difficulty
+}
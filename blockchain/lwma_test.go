@@ -0,0 +1,83 @@
+package blockchain
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// Adds an instant sealed block at the chain's difficulty, timestamped
+// solveTime seconds after the tip
+func addTimedBlock(t *testing.T, bc *Blockchain, solveTime int64) {
+	t.Helper()
+
+	tip := tipBlock(t, bc)
+	count, _ := bc.GetBlockCount()
+
+	block := newUnsealedBlock([]*Transaction{NewCoinbaseTX("bob", "", count)}, tip.Hash, bc.Difficulty())
+	block.Timestamp = tip.Timestamp + solveTime
+	if err := bc.seal(block); err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.AddBlock(block); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNextDifficultyLWMAConverges(t *testing.T) {
+	// a miner that takes targetBlockTime on average at 12 bits
+	const equilibrium = 12
+	hashrate := math.Exp2(equilibrium) / targetBlockTime
+	rng := rand.New(rand.NewSource(1))
+
+	for _, start := range []int{6, 18} {
+		bc := newTestChain(t, WithDevMode(), WithInstantSeal())
+		if err := bc.SetDifficulty(start); err != nil {
+			t.Fatal(err)
+		}
+
+		for i := 0; i < 3*lwmaWindow; i++ {
+			expected := math.Exp2(float64(bc.Difficulty())) / hashrate
+			// solve times vary between half and one and a half times the expected
+			addTimedBlock(t, bc, int64(math.Max(1, expected*(0.5+rng.Float64()))))
+
+			next, err := bc.NextDifficultyLWMA()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := next - bc.Difficulty(); diff > 1 || diff < -1 {
+				t.Fatalf("NextDifficultyLWMA() moved from %d to %d bits in one block", bc.Difficulty(), next)
+			}
+			if err := bc.SetDifficulty(next); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if got := bc.Difficulty(); got < equilibrium-1 || got > equilibrium+1 {
+			t.Errorf("difficulty starting at %d bits settled at %d, want about %d", start, got, equilibrium)
+		}
+		bc.Db.Close()
+	}
+}
+
+func TestNextDifficultyLWMABoundsTimestamps(t *testing.T) {
+	bc := newTestChain(t, WithDevMode(), WithInstantSeal())
+	if err := bc.SetDifficulty(12); err != nil {
+		t.Fatal(err)
+	}
+
+	// a huge solve time counts as six target intervals, which over a full
+	// window of on-target blocks isn't enough to lower the difficulty
+	for i := 0; i < lwmaWindow-1; i++ {
+		addTimedBlock(t, bc, targetBlockTime)
+	}
+	addTimedBlock(t, bc, 1000*targetBlockTime)
+
+	next, err := bc.NextDifficultyLWMA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next != 12 {
+		t.Errorf("NextDifficultyLWMA() = %d after one skewed timestamp, want 12", next)
+	}
+}
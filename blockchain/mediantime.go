@@ -0,0 +1,57 @@
+package blockchain
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/boltdb/bolt"
+)
+
+// Number of blocks whose timestamps make up the median time past
+const medianTimeBlocks = 11
+
+var ErrTimeTooOld = errors.New("block timestamp is not after the median time of the previous blocks")
+
+// The median timestamp of the last medianTimeBlocks blocks up to the tip,
+// or of all of them on a shorter chain. A new block has to be timestamped
+// after it, so a miner can't set its clock back by much.
+func (bc *Blockchain) MedianTimePast() (int64, error) {
+	bc.mu.RLock()
+	tip := bc.tip
+	bc.mu.RUnlock()
+
+	return bc.medianTimePastAt(tip)
+}
+
+func (bc *Blockchain) medianTimePastAt(hash []byte) (int64, error) {
+	var median int64
+
+	err := bc.Db.View(func(tx *bolt.Tx) error {
+		var err error
+		median, err = medianTimePast(tx.Bucket([]byte(blocksBucket)), hash)
+		return err
+	})
+
+	return median, err
+}
+
+// Computes the median time past of the chain ending at hash
+func medianTimePast(b *bolt.Bucket, hash []byte) (int64, error) {
+	var timestamps []int64
+
+	for len(timestamps) < medianTimeBlocks && len(hash) != 0 {
+		block, err := decodeBlockRecord(b.Get(hash))
+		if err != nil {
+			return 0, err
+		}
+
+		timestamps = append(timestamps, block.Timestamp)
+		hash = block.PrevBlockHash
+	}
+
+	sort.Slice(timestamps, func(i, j int) bool {
+		return timestamps[i] < timestamps[j]
+	})
+
+	return timestamps[len(timestamps)/2], nil
+}
@@ -0,0 +1,38 @@
+package blockchain
+
+import "testing"
+
+func TestMedianTimePast(t *testing.T) {
+	bc := newTestChain(t, WithDevMode(), WithInstantSeal())
+	genesis := tipBlock(t, bc)
+
+	// the last eleven blocks are 10 to 110 seconds after genesis
+	for i := 0; i < medianTimeBlocks; i++ {
+		addTimedBlock(t, bc, 10)
+	}
+	median, err := bc.MedianTimePast()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if median != genesis.Timestamp+60 {
+		t.Fatalf("MedianTimePast() = %d, want %d", median, genesis.Timestamp+60)
+	}
+
+	blockAt := func(timestamp int64) *Block {
+		count, _ := bc.GetBlockCount()
+		block := newUnsealedBlock([]*Transaction{NewCoinbaseTX("bob", "", count)}, bc.GetBestBlockHash(), bc.Difficulty())
+		block.Timestamp = timestamp
+		if err := bc.seal(block); err != nil {
+			t.Fatal(err)
+		}
+		return block
+	}
+
+	if err := bc.AddBlock(blockAt(median)); err != ErrTimeTooOld {
+		t.Errorf("AddBlock() = %v for a block at the median time past, want ErrTimeTooOld", err)
+	}
+	// earlier than the tip is fine as long as it is after the median
+	if err := bc.AddBlock(blockAt(median + 1)); err != nil {
+		t.Errorf("AddBlock() = %v for a block just after the median time past", err)
+	}
+}
@@ -0,0 +1,92 @@
+package blockchain
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/boltdb/bolt"
+)
+
+// Holds chain-wide settings that aren't part of any block
+const metaBucket = "meta"
+
+const difficultyKey = "difficulty"
+
+// Range of target bits SetDifficulty accepts
+const (
+	minDifficulty = 1
+	maxDifficulty = 32
+)
+
+var ErrDevModeRequired = errors.New("this setting is only available in DevMode")
+
+var ErrDifficultyRange = errors.New("difficulty must be between 1 and 32 bits")
+
+func getMetaInt(tx *bolt.Tx, key string) (int, bool) {
+	b := tx.Bucket([]byte(metaBucket))
+	if b == nil {
+		return 0, false
+	}
+
+	value := b.Get([]byte(key))
+	if len(value) != 8 {
+		return 0, false
+	}
+
+	return int(binary.BigEndian.Uint64(value)), true
+}
+
+func putMetaInt(tx *bolt.Tx, key string, value int) error {
+	b, err := tx.CreateBucketIfNotExists([]byte(metaBucket))
+	if err != nil {
+		return err
+	}
+
+	encoded := make([]byte, 8)
+	binary.BigEndian.PutUint64(encoded, uint64(value))
+
+	return b.Put([]byte(key), encoded)
+}
+
+// Rejects dev-only settings on a chain that isn't in DevMode
+func (bc *Blockchain) checkDevMode() error {
+	if _, ok := bc.consensus.(instantSeal); ok && !bc.devMode {
+		return ErrDevModeRequired
+	}
+	if bc.seededNonce && !bc.devMode {
+		return ErrDevModeRequired
+	}
+
+	return nil
+}
+
+// Target bits the next block will be mined at
+func (bc *Blockchain) Difficulty() int {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	return bc.difficulty
+}
+
+// Changes the difficulty of subsequent blocks. Only allowed in DevMode.
+func (bc *Blockchain) SetDifficulty(bits int) error {
+	if !bc.devMode {
+		return ErrDevModeRequired
+	}
+	if bits < minDifficulty || bits > maxDifficulty {
+		return ErrDifficultyRange
+	}
+
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	err := bc.Db.Update(func(tx *bolt.Tx) error {
+		return putMetaInt(tx, difficultyKey, bits)
+	})
+	if err != nil {
+		return err
+	}
+	bc.difficulty = bits
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package blockchain
+
+import "testing"
+
+func TestSetDifficulty(t *testing.T) {
+	bc := newTestChain(t, WithDevMode())
+
+	if err := bc.SetDifficulty(4); err != nil {
+		t.Fatal(err)
+	}
+	if block := mineTestBlock(t, bc, "bob"); block.Difficulty != 4 {
+		t.Errorf("next block mined at %d bits, want 4", block.Difficulty)
+	}
+
+	for _, bits := range []int{minDifficulty - 1, maxDifficulty + 1} {
+		if err := bc.SetDifficulty(bits); err != ErrDifficultyRange {
+			t.Errorf("SetDifficulty(%d) = %v, want ErrDifficultyRange", bits, err)
+		}
+	}
+
+	bc = reopenTestChain(t, bc)
+	if got := bc.Difficulty(); got != 4 {
+		t.Errorf("Difficulty() = %d after reopening, want 4", got)
+	}
+	if err := bc.SetDifficulty(8); err != ErrDevModeRequired {
+		t.Errorf("SetDifficulty() = %v outside DevMode, want ErrDevModeRequired", err)
+	}
+}
@@ -0,0 +1,51 @@
+package blockchain
+
+import (
+	"fmt"
+
+	"github.com/boltdb/bolt"
+)
+
+// Version of the on-disk layout this code reads and writes. Databases
+// without a stored version predate the meta bucket and count as version 1.
+const schemaVersion = 2
+
+const schemaVersionKey = "version"
+
+// Upgrades a database from one schema version to the next
+type migration func(tx *bolt.Tx) error
+
+// migrations[i] upgrades version i+1 to version i+2. Append to add a version
+// and bump schemaVersion with it.
+var migrations = []migration{
+	// 1 -> 2: the version is recorded for the first time; nothing else changes
+	func(tx *bolt.Tx) error {
+		return nil
+	},
+}
+
+// Brings the database up to schemaVersion, running each pending migration
+// in order within the caller's transaction
+func migrate(tx *bolt.Tx) error {
+	version, ok := getMetaInt(tx, schemaVersionKey)
+	if !ok {
+		version = 1
+	}
+
+	if version > schemaVersion {
+		return fmt.Errorf("database schema version %d is newer than this program supports (%d)", version, schemaVersion)
+	}
+
+	for ; version < schemaVersion; version++ {
+		logger.Infof("Migrating database from schema version %d to %d", version, version+1)
+
+		if err := migrations[version-1](tx); err != nil {
+			return fmt.Errorf("migrating to schema version %d: %w", version+1, err)
+		}
+		if err := putMetaInt(tx, schemaVersionKey, version+1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package blockchain
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+func setSchemaVersion(t *testing.T, bc *Blockchain, version int) {
+	t.Helper()
+
+	err := bc.Db.Update(func(tx *bolt.Tx) error {
+		return putMetaInt(tx, schemaVersionKey, version)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpeningOldDatabaseMigrates(t *testing.T) {
+	bc := newTestChain(t)
+	setSchemaVersion(t, bc, 1)
+
+	ran := 0
+	defer func(saved []migration) {
+		migrations = saved
+	}(migrations)
+	migrations = []migration{
+		func(tx *bolt.Tx) error {
+			ran++
+			return nil
+		},
+	}
+
+	bc = reopenTestChain(t, bc)
+
+	if ran != 1 {
+		t.Errorf("migration ran %d times, want once", ran)
+	}
+	bc.Db.View(func(tx *bolt.Tx) error {
+		if version, _ := getMetaInt(tx, schemaVersionKey); version != schemaVersion {
+			t.Errorf("stored version = %d after migrating, want %d", version, schemaVersion)
+		}
+		return nil
+	})
+
+	bc = reopenTestChain(t, bc)
+	if ran != 1 {
+		t.Errorf("migration ran again on a current database")
+	}
+}
+
+func TestOpeningNewerDatabaseFails(t *testing.T) {
+	bc := newTestChain(t)
+	setSchemaVersion(t, bc, schemaVersion+1)
+	bc.Db.Close()
+
+	_, err := NewBlockchain("alice")
+	if err == nil || !strings.Contains(err.Error(), "newer than this program supports") {
+		t.Errorf("NewBlockchain() = %v for a newer schema version", err)
+	}
+}
@@ -0,0 +1,144 @@
+package blockchain
+
+import "io"
+
+// Configures a Blockchain when it is created or opened
+type Option func(*Blockchain)
+
+// Gzips block records before storing them. Records already in the database
+// are read back either way, so the option can be turned on or off at any time.
+func WithCompression() Option {
+	return func(bc *Blockchain) {
+		bc.compress = true
+	}
+}
+
+// Resets a tip that doesn't point at a readable block when the chain is
+// opened, instead of failing with ErrCorruptTip. A tip that is readable but
+// not on the best stored chain is reset either way.
+func WithTipRepair() Option {
+	return func(bc *Blockchain) {
+		bc.repairCorruptTip = true
+	}
+}
+
+// Stores block records as gob (the default), JSON or the compact binary
+// encoding. Like WithGenesisData it only applies when the chain is created:
+// the format is kept with the chain and used for all its blocks.
+// WithCompression only affects gob records.
+func WithStorageFormat(format StorageFormat) Option {
+	return func(bc *Blockchain) {
+		bc.format = format
+	}
+}
+
+// Uses data as the genesis coinbase data instead of the default headline,
+// giving the chain a distinct genesis block. It only applies when the chain
+// is created; an existing chain keeps the genesis it was created with.
+func WithGenesisData(data string) Option {
+	return func(bc *Blockchain) {
+		bc.genesisData = data
+	}
+}
+
+// Pays each address the allocated amount in the genesis block, on top of
+// the usual reward, so a test chain starts with balances. Like
+// WithGenesisData it only applies when the chain is created.
+func WithAllocations(allocs map[string]int) Option {
+	return func(bc *Blockchain) {
+		bc.allocations = allocs
+	}
+}
+
+// Mines the chain at the given target bits instead of targetBits. It only
+// applies when the chain is created; an existing chain keeps the difficulty
+// stored with it, which SetDifficulty can change in DevMode.
+func WithDifficulty(bits int) Option {
+	return func(bc *Blockchain) {
+		bc.difficulty = bits
+	}
+}
+
+// Keeps up to size outputs resolved by GetOutput in memory, so fee
+// calculations don't look them up in the chain again. 0 turns the cache off.
+func WithPrevoutCacheSize(size int) Option {
+	return func(bc *Blockchain) {
+		bc.prevouts.size = size
+	}
+}
+
+// Only spends outputs with at least n confirmations. The default of 1 makes
+// outputs spendable as soon as their block is mined.
+func WithMinConfirmations(n int) Option {
+	return func(bc *Blockchain) {
+		bc.minConfirmations = n
+	}
+}
+
+// Sets aside up to weight units of every mined block for the transactions
+// with the highest coin-age priority, whatever fee they pay. The rest of the
+// block is filled by fee rate as usual.
+func WithPrioritySpace(weight int) Option {
+	return func(bc *Blockchain) {
+		bc.prioritySpace = weight
+	}
+}
+
+// Starts each nonce search at a value derived from the block being mined
+// instead of 0, so tests can rely on the nonce a given block ends up with
+// at a low difficulty. Only allowed together with WithDevMode.
+func WithSeededNonce() Option {
+	return func(bc *Blockchain) {
+		bc.seededNonce = true
+	}
+}
+
+// Appends a JSON line with the hash, height, timestamp and transaction
+// count of every block added to the chain to the file at path. Once the
+// file reaches maxSize bytes it is moved to path.1 and a new one started;
+// 0 never rotates it.
+func WithAuditLog(path string, maxSize int64) Option {
+	return func(bc *Blockchain) {
+		bc.audit = &auditLog{path: path, maxSize: maxSize}
+	}
+}
+
+// Writes the mining messages of proof of work blocks to w instead of the
+// package logger, so they can be captured or kept apart from other output
+func WithMiningOutput(w io.Writer) Option {
+	return func(bc *Blockchain) {
+		bc.miningOutput = w
+	}
+}
+
+// Seals and verifies blocks with c instead of the default proof of work.
+// A chain has to be opened with the consensus it was created with.
+func WithConsensus(c Consensus) Option {
+	return func(bc *Blockchain) {
+		bc.consensus = c
+	}
+}
+
+// Picks the transactions of mined blocks with a instead of the default
+// FeeRateAssembler, e.g. FIFOAssembler
+func WithBlockAssembler(a BlockAssembler) Option {
+	return func(bc *Blockchain) {
+		bc.assembler = a
+	}
+}
+
+// Development mode, which unlocks settings that must never apply to a real
+// chain, such as WithInstantSeal and SetDifficulty
+func WithDevMode() Option {
+	return func(bc *Blockchain) {
+		bc.devMode = true
+	}
+}
+
+// Seals blocks instantly instead of mining them. Only allowed together with
+// WithDevMode. A chain created this way can only be opened with it again.
+func WithInstantSeal() Option {
+	return func(bc *Blockchain) {
+		bc.consensus = instantSeal{}
+	}
+}
@@ -0,0 +1,89 @@
+package blockchain
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+)
+
+// Number of resolved outputs kept by default, see WithPrevoutCacheSize
+const defaultPrevoutCacheSize = 1024
+
+// Least recently used cache of outputs resolved by GetOutput, so fee
+// calculations don't look up the same previous outputs over and over
+type prevoutCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // most recently used at the front
+	entries map[string]*list.Element
+}
+
+type prevoutEntry struct {
+	key string
+	out TXOutput
+}
+
+func prevoutKey(txid []byte, vout int) string {
+	return fmt.Sprintf("%x:%d", txid, vout)
+}
+
+func (c *prevoutCache) get(txid []byte, vout int) (TXOutput, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[prevoutKey(txid, vout)]
+	if !ok {
+		return TXOutput{}, false
+	}
+	c.order.MoveToFront(elem)
+
+	return elem.Value.(*prevoutEntry).out, true
+}
+
+func (c *prevoutCache) add(txid []byte, vout int, out TXOutput) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.size <= 0 {
+		return
+	}
+	if c.entries == nil {
+		c.entries = make(map[string]*list.Element)
+		c.order = list.New()
+	}
+
+	key := prevoutKey(txid, vout)
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*prevoutEntry).out = out
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&prevoutEntry{key, out})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*prevoutEntry).key)
+	}
+}
+
+// Drops the outputs a block spends, which no pending transaction can use
+// any more
+func (c *prevoutCache) removeSpent(block *Block) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, tx := range block.Transactions {
+		if tx.IsCoinbase() {
+			continue
+		}
+
+		for _, in := range tx.Vin {
+			key := prevoutKey(in.Txid, in.Vout)
+			if elem, ok := c.entries[key]; ok {
+				c.order.Remove(elem)
+				delete(c.entries, key)
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package blockchain
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPrevoutCacheMatchesLookups(t *testing.T) {
+	bc := newTestChain(t)
+	for i := 0; i < 3; i++ {
+		mineTestBlock(t, bc, "alice")
+	}
+	tx := spendTestTX(t, bc, "alice", "bob", 25, 2)
+
+	cached, err := bc.TransactionFee(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bc.prevouts.entries) != len(tx.Vin) {
+		t.Errorf("cache holds %d outputs after resolving %d inputs", len(bc.prevouts.entries), len(tx.Vin))
+	}
+	for _, in := range tx.Vin {
+		out, ok := bc.prevouts.get(in.Txid, in.Vout)
+		if !ok || out != (TXOutput{subsidy, "alice"}) {
+			t.Errorf("cached output %x:%d = %+v, %v", in.Txid, in.Vout, out, ok)
+		}
+	}
+
+	bc = reopenTestChain(t, bc, WithPrevoutCacheSize(0))
+	fresh, err := bc.TransactionFee(tx)
+	if err != nil || fresh != cached {
+		t.Errorf("TransactionFee() = %d, %v without the cache, %d with it", fresh, err, cached)
+	}
+	if len(bc.prevouts.entries) != 0 {
+		t.Errorf("disabled cache holds %d outputs", len(bc.prevouts.entries))
+	}
+}
+
+func TestPrevoutCacheEviction(t *testing.T) {
+	c := prevoutCache{size: 2}
+	c.add([]byte("a"), 0, TXOutput{1, "alice"})
+	c.add([]byte("b"), 0, TXOutput{2, "alice"})
+	c.get([]byte("a"), 0)
+	c.add([]byte("c"), 0, TXOutput{3, "alice"})
+
+	if _, ok := c.get([]byte("b"), 0); ok {
+		t.Error("least recently used output still cached")
+	}
+	if _, ok := c.get([]byte("a"), 0); !ok {
+		t.Error("recently used output evicted")
+	}
+
+	spending := Transaction{nil, []TXInput{{[]byte("a"), 0, "alice"}}, []TXOutput{{1, "bob"}}}
+	c.removeSpent(&Block{Transactions: []*Transaction{&spending}})
+	if _, ok := c.get([]byte("a"), 0); ok {
+		t.Error("spent output still cached")
+	}
+}
+
+// Resolving the same inputs again, as block assembly does for every
+// candidate ordering, hits the cache instead of the chain
+func BenchmarkTransactionFee(b *testing.B) {
+	for _, size := range []int{0, defaultPrevoutCacheSize} {
+		b.Run(fmt.Sprintf("cache=%d", size), func(b *testing.B) {
+			bc := newTestChain(b, WithPrevoutCacheSize(size))
+			for i := 0; i < 10; i++ {
+				mineTestBlock(b, bc, "alice")
+			}
+			tx := spendTestTX(b, bc, "alice", "bob", 50, 1)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := bc.TransactionFee(tx); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
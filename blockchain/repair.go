@@ -0,0 +1,135 @@
+package blockchain
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/boltdb/bolt"
+)
+
+var ErrNoValidBlocks = errors.New("no block in the database links back to a valid genesis block")
+
+var ErrCorruptTip = errors.New("chain tip does not point at a readable block, run repair to reset it")
+
+// Checks that the "l" key points at a block on the best stored chain and,
+// if it doesn't, resets it to the highest block that links back to genesis.
+// Reports whether the tip had to be repaired.
+func (bc *Blockchain) RepairTip() (bool, error) {
+	repaired := false
+
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	err := bc.Db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(blocksBucket))
+		if b == nil {
+			return errors.New("error repairing tip, blocks bucket does not exist")
+		}
+
+		heights, err := bc.blockHeights(b)
+		if err != nil {
+			return err
+		}
+
+		var bestHash []byte
+		bestHeight := -1
+		for hash, height := range heights {
+			// ties are broken by hash so the repair is deterministic
+			if height > bestHeight || (height == bestHeight && bytes.Compare([]byte(hash), bestHash) < 0) {
+				bestHash = []byte(hash)
+				bestHeight = height
+			}
+		}
+
+		if bestHeight < 0 {
+			return ErrNoValidBlocks
+		}
+
+		bc.height = bestHeight
+
+		tip := b.Get([]byte("l"))
+		if height, ok := heights[string(tip)]; ok && height == bestHeight {
+			return syncHeightIndex(tx, tip, bestHeight)
+		}
+
+		if err := b.Put([]byte("l"), bestHash); err != nil {
+			return err
+		}
+		bc.tip = bestHash
+		repaired = true
+
+		return rebuildHeightIndex(tx, bestHash, bestHeight)
+	})
+
+	return repaired, err
+}
+
+// Computes the height of every stored block that links back to genesis
+// through valid blocks. Blocks that don't are left out of the result.
+func (bc *Blockchain) blockHeights(b *bolt.Bucket) (map[string]int, error) {
+	blocks := make(map[string]*Block)
+
+	err := b.ForEach(func(k, v []byte) error {
+		if bytes.Equal(k, []byte("l")) {
+			return nil
+		}
+
+		block, err := decodeBlockRecord(v)
+		if err != nil || !bytes.Equal(block.Hash, k) {
+			// unreadable or misfiled records can't be part of the chain
+			return nil
+		}
+		blocks[string(k)] = block
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	heights := make(map[string]int)
+
+	var heightOf func(hash string) int
+	heightOf = func(hash string) int {
+		if height, ok := heights[hash]; ok {
+			return height
+		}
+		// marks the block as in progress so a cycle resolves to invalid
+		heights[hash] = -1
+
+		block, ok := blocks[hash]
+		if !ok {
+			return -1
+		}
+
+		height := 0
+		var prev *Block
+		if len(block.PrevBlockHash) != 0 {
+			prevHeight := heightOf(string(block.PrevBlockHash))
+			if prevHeight < 0 {
+				return -1
+			}
+			height = prevHeight + 1
+			prev = blocks[string(block.PrevBlockHash)]
+		}
+
+		if bc.validateBlock(block, prev) != nil {
+			return -1
+		}
+		heights[hash] = height
+
+		return height
+	}
+
+	for hash := range blocks {
+		heightOf(hash)
+	}
+
+	for hash, height := range heights {
+		if height < 0 {
+			delete(heights, hash)
+		}
+	}
+
+	return heights, nil
+}
@@ -0,0 +1,78 @@
+package blockchain
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRepairTipRecoversStaleTip(t *testing.T) {
+	bc := newTestChain(t)
+	genesis := bc.GetBestBlockHash()
+	mineTestBlock(t, bc, "bob")
+	best := mineTestBlock(t, bc, "bob")
+
+	// a tip left behind by an interrupted write still points at a readable block
+	setTip(t, bc, genesis)
+	bc = reopenTestChain(t, bc)
+
+	if !bc.TipRepaired() {
+		t.Error("TipRepaired() = false after opening a chain with a stale tip")
+	}
+	if !bytes.Equal(bc.GetBestBlockHash(), best.Hash) {
+		t.Errorf("tip = %x, want the highest block %x", bc.GetBestBlockHash(), best.Hash)
+	}
+	if count, _ := bc.GetBlockCount(); count != 3 {
+		t.Errorf("GetBlockCount() = %d, want 3", count)
+	}
+
+	repaired, err := bc.RepairTip()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repaired {
+		t.Error("RepairTip() repaired a tip that was already consistent")
+	}
+}
+
+func TestRepairTipLeavesConsistentTip(t *testing.T) {
+	bc := newTestChain(t)
+	mineTestBlock(t, bc, "bob")
+
+	bc = reopenTestChain(t, bc)
+
+	if bc.TipRepaired() {
+		t.Error("TipRepaired() = true for a consistent chain")
+	}
+}
+
+func TestNewBlockchainRejectsCorruptTip(t *testing.T) {
+	bc := newTestChain(t)
+	mineTestBlock(t, bc, "bob")
+	setTip(t, bc, []byte("not a block hash"))
+	bc.Db.Close()
+
+	_, err := NewBlockchain("alice")
+	if !errors.Is(err, ErrCorruptTip) {
+		t.Fatalf("NewBlockchain() error = %v, want ErrCorruptTip", err)
+	}
+	if !strings.Contains(err.Error(), "run repair") {
+		t.Errorf("error %q does not say how to recover", err)
+	}
+}
+
+func TestWithTipRepairResetsCorruptTip(t *testing.T) {
+	bc := newTestChain(t)
+	best := mineTestBlock(t, bc, "bob")
+	setTip(t, bc, []byte("not a block hash"))
+
+	bc = reopenTestChain(t, bc, WithTipRepair())
+
+	if !bc.TipRepaired() {
+		t.Error("TipRepaired() = false after resetting a corrupt tip")
+	}
+	if !bytes.Equal(bc.GetBestBlockHash(), best.Hash) {
+		t.Errorf("tip = %x, want %x", bc.GetBestBlockHash(), best.Hash)
+	}
+}
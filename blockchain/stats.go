@@ -0,0 +1,60 @@
+package blockchain
+
+// Summary figures for a single block
+type BlockStats struct {
+	Transactions int
+	InputValue   int
+	OutputValue  int
+	Fees         int
+	Size         int
+	MerkleRoot   []byte
+
+	// see ProofOfWork.Difficulty
+	Difficulty float64
+}
+
+// Computes the statistics of the block with the given hash. Coinbase inputs
+// carry no value, so a coinbase-only block reports no inputs and no fees.
+func (bc *Blockchain) BlockStats(hash []byte) (*BlockStats, error) {
+	block, err := bc.GetBlock(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	serialized, err := block.Serialize()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &BlockStats{
+		Transactions: len(block.Transactions),
+		Size:         len(serialized),
+		MerkleRoot:   block.HashTransactions(),
+		Difficulty:   NewProofOfWork(block).Difficulty(),
+	}
+
+	for _, tx := range block.Transactions {
+		outputs := 0
+		for _, out := range tx.Vout {
+			outputs += out.Value
+		}
+		stats.OutputValue += outputs
+
+		if tx.IsCoinbase() {
+			continue
+		}
+
+		inputs := 0
+		for _, in := range tx.Vin {
+			prevOut, err := bc.GetOutput(in.Txid, in.Vout)
+			if err != nil {
+				return nil, err
+			}
+			inputs += prevOut.Value
+		}
+		stats.InputValue += inputs
+		stats.Fees += inputs - outputs
+	}
+
+	return stats, nil
+}
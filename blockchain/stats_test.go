@@ -0,0 +1,42 @@
+package blockchain
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBlockStats(t *testing.T) {
+	bc := newTestChain(t, WithAllocations(map[string]int{"alice": 100, "bob": 50}))
+	genesis := tipBlock(t, bc)
+
+	stats, err := bc.BlockStats(genesis.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Fees != 0 || stats.InputValue != 0 {
+		t.Errorf("genesis stats report fees %d and inputs %d, want none", stats.Fees, stats.InputValue)
+	}
+
+	// alice spends 110 and bob 50
+	block := mineTestBlock(t, bc, "carol",
+		spendTestTX(t, bc, "alice", "carol", 20, 3),
+		spendTestTX(t, bc, "bob", "carol", 10, 2))
+
+	stats, err = bc.BlockStats(block.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serialized, _ := block.Serialize()
+	want := BlockStats{
+		Transactions: 3,
+		InputValue:   160,
+		OutputValue:  160 - 5 + subsidy,
+		Fees:         5,
+		Size:         len(serialized),
+		MerkleRoot:   block.HashTransactions(),
+		Difficulty:   NewProofOfWork(block).Difficulty(),
+	}
+	if !reflect.DeepEqual(*stats, want) {
+		t.Errorf("BlockStats() = %+v, want %+v", *stats, want)
+	}
+}
@@ -0,0 +1,102 @@
+package blockchain
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// Flag bytes prefixed to stored block records. Records written before the
+// flag existed start straight with gob data, whose first byte is never in
+// the 0x80-0xf7 range, so they are still told apart and read as plain gob.
+const (
+	recordPlain   byte = 0x80
+	recordGzipped byte = 0x81
+	recordJSON    byte = 0x82
+	recordCompact byte = 0x83
+)
+
+// How block records are encoded, see WithStorageFormat
+type StorageFormat int
+
+const (
+	FormatGob StorageFormat = iota
+	FormatJSON
+	FormatCompact
+)
+
+const storageFormatKey = "format"
+
+var ErrStorageFormat = errors.New("unknown block storage format")
+
+// Encodes a block for storage in the blocks bucket. Gob records are
+// gzipped when compress is set; the other formats are stored as they are.
+func encodeBlockRecord(block *Block, format StorageFormat, compress bool) ([]byte, error) {
+	var record bytes.Buffer
+
+	switch format {
+	case FormatGob:
+	case FormatJSON:
+		encoded, err := json.Marshal(block)
+		if err != nil {
+			return nil, err
+		}
+
+		return append([]byte{recordJSON}, encoded...), nil
+	case FormatCompact:
+		return append([]byte{recordCompact}, block.MarshalCompact()...), nil
+	default:
+		return nil, ErrStorageFormat
+	}
+
+	if !compress {
+		record.WriteByte(recordPlain)
+		_, err := block.WriteTo(&record)
+
+		return record.Bytes(), err
+	}
+
+	record.WriteByte(recordGzipped)
+	zw := gzip.NewWriter(&record)
+	if _, err := block.WriteTo(zw); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return record.Bytes(), nil
+}
+
+// Decodes a record from the blocks bucket, whichever way it was stored
+func decodeBlockRecord(record []byte) (*Block, error) {
+	if len(record) == 0 {
+		return nil, errors.New("empty block record")
+	}
+
+	switch record[0] {
+	case recordPlain:
+		return deserializeBlock(record[1:])
+	case recordGzipped:
+		zr, err := gzip.NewReader(bytes.NewReader(record[1:]))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+
+		return ReadBlockFrom(io.Reader(zr))
+	case recordJSON:
+		block := &Block{}
+		if err := json.Unmarshal(record[1:], block); err != nil {
+			return nil, err
+		}
+
+		return block, nil
+	case recordCompact:
+		return UnmarshalCompactBlock(record[1:])
+	default:
+		return deserializeBlock(record)
+	}
+}
@@ -0,0 +1,122 @@
+package blockchain
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestCompressedRecordsAreSmaller(t *testing.T) {
+	SetLogger(NopLogger)
+	block := newUnsealedBlock([]*Transaction{NewCoinbaseTX("alice", strings.Repeat("a", 10000), 0)}, nil, 8)
+
+	plain, err := encodeBlockRecord(block, FormatGob, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed, err := encodeBlockRecord(block, FormatGob, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(compressed) >= len(plain) {
+		t.Errorf("compressed record is %d bytes, plain %d", len(compressed), len(plain))
+	}
+}
+
+func TestMixedRecordsReadBack(t *testing.T) {
+	bc := newTestChain(t)
+	plain := mineTestBlock(t, bc, "bob")
+
+	bc = reopenTestChain(t, bc, WithCompression())
+	compressed := mineTestBlock(t, bc, "bob")
+
+	var plainFlag, compressedFlag byte
+	bc.Db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(blocksBucket))
+		plainFlag = b.Get(plain.Hash)[0]
+		compressedFlag = b.Get(compressed.Hash)[0]
+		return nil
+	})
+	if compressedFlag != recordGzipped {
+		t.Errorf("block mined with WithCompression stored with flag %#x", compressedFlag)
+	}
+	if plainFlag != recordPlain {
+		t.Errorf("block mined without WithCompression stored with flag %#x", plainFlag)
+	}
+
+	// records from before the flag byte are plain gob
+	legacy, err := plain.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = bc.Db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(blocksBucket)).Put(plain.Hash, legacy)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []*Block{plain, compressed} {
+		got, err := bc.GetBlock(want.Hash)
+		if err != nil {
+			t.Fatalf("GetBlock(%x) = %v", want.Hash, err)
+		}
+		if !bytes.Equal(got.Hash, want.Hash) || got.Nonce != want.Nonce {
+			t.Errorf("GetBlock(%x) read back a different block", want.Hash)
+		}
+	}
+}
+
+func TestStorageFormatsReadBack(t *testing.T) {
+	formats := []struct {
+		name   string
+		format StorageFormat
+		flag   byte
+	}{
+		{"gob", FormatGob, recordPlain},
+		{"json", FormatJSON, recordJSON},
+		{"compact", FormatCompact, recordCompact},
+	}
+
+	for _, tt := range formats {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := newTestChain(t, WithStorageFormat(tt.format))
+			mined := []*Block{tipBlock(t, bc), mineTestBlock(t, bc, "carol", spendTestTX(t, bc, "alice", "bob", 4, 1))}
+
+			// the format is kept with the chain, so a different one is ignored
+			other := FormatJSON
+			if tt.format == FormatJSON {
+				other = FormatCompact
+			}
+			bc = reopenTestChain(t, bc, WithStorageFormat(other))
+			mined = append(mined, mineTestBlock(t, bc, "carol"))
+
+			for _, want := range mined {
+				var flag byte
+				bc.Db.View(func(tx *bolt.Tx) error {
+					flag = tx.Bucket([]byte(blocksBucket)).Get(want.Hash)[0]
+					return nil
+				})
+				if flag != tt.flag {
+					t.Errorf("block %x stored with flag %#x, want %#x", want.Hash, flag, tt.flag)
+				}
+
+				got, err := bc.GetBlock(want.Hash)
+				if err != nil {
+					t.Fatalf("GetBlock(%x) = %v", want.Hash, err)
+				}
+				if !reflect.DeepEqual(withoutEmptySlices(got), withoutEmptySlices(want)) {
+					t.Errorf("GetBlock(%x) = %+v, want %+v", want.Hash, got, want)
+				}
+			}
+
+			if got := balance(t, bc, "bob"); got != 4 {
+				t.Errorf("balance of bob = %d, want 4", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package blockchain
+
+import (
+	"errors"
+)
+
+// Fee left to the miner when sweeping an address
+const sweepFee = 1
+
+var ErrNothingToSweep = errors.New("address has no spendable balance above the sweep fee")
+
+var ErrNotMined = errors.New("transaction was left out of the mined block")
+
+// Moves every spendable output of from to a single output paying to, less
+// sweepFee, and mines the transaction. There is no change output. The
+// block's coinbase reward also goes to to.
+func SweepAddress(from, to string, bc *Blockchain) (*Transaction, error) {
+	outPoints, err := bc.SpendableOutputs(from)
+	if err != nil {
+		return nil, err
+	}
+
+	if totalValue(outPoints) <= sweepFee {
+		return nil, ErrNothingToSweep
+	}
+
+	return spendOutputs(from, to, outPoints, bc)
+}
+
+// Spends the given outputs of from in a single output paying to, less
+// sweepFee, and mines the transaction in a block whose coinbase reward also
+// goes to to. The outputs must be worth more than sweepFee. Returns
+// ErrNotMined if the chain's assembler leaves the transaction out.
+func spendOutputs(from, to string, outPoints []OutPoint, bc *Blockchain) (*Transaction, error) {
+	var inputs []TXInput
+	for _, out := range outPoints {
+		inputs = append(inputs, TXInput{out.Txid, out.Index, from})
+	}
+
+	tx := Transaction{nil, inputs, []TXOutput{{totalValue(outPoints) - sweepFee, to}}}
+	tx.SetID()
+
+	height, err := bc.GetBlockCount()
+	if err != nil {
+		return nil, err
+	}
+	cbtx := NewCoinbaseTX(to, "", height)
+
+	excluded, err := bc.MineBlock([]*Transaction{cbtx, &tx})
+	if err != nil {
+		return nil, err
+	}
+	for _, left := range excluded {
+		if left == &tx {
+			return nil, ErrNotMined
+		}
+	}
+
+	return &tx, nil
+}
+
+func totalValue(outPoints []OutPoint) int {
+	total := 0
+	for _, out := range outPoints {
+		total += out.Value
+	}
+
+	return total
+}
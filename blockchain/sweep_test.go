@@ -0,0 +1,58 @@
+package blockchain
+
+import "testing"
+
+func TestSweepAddress(t *testing.T) {
+	bc := newTestChain(t)
+	mineTestBlock(t, bc, "alice")
+	swept := balance(t, bc, "alice")
+
+	tx, err := SweepAddress("alice", "bob", bc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := balance(t, bc, "alice"); got != 0 {
+		t.Errorf("balance of alice = %d after the sweep, want 0", got)
+	}
+	if len(tx.Vout) != 1 || tx.Vout[0].ScriptPubKey != "bob" || tx.Vout[0].Value != swept-sweepFee {
+		t.Errorf("sweep outputs = %+v, want a single output of %d to bob", tx.Vout, swept-sweepFee)
+	}
+	// the reward for the block carrying the sweep also goes to bob
+	if got := balance(t, bc, "bob"); got != swept-sweepFee+subsidy {
+		t.Errorf("balance of bob = %d, want the swept %d and the block reward", got, swept-sweepFee)
+	}
+}
+
+func TestSweepEmptyAddress(t *testing.T) {
+	bc := newTestChain(t)
+
+	if _, err := SweepAddress("bob", "carol", bc); err != ErrNothingToSweep {
+		t.Errorf("SweepAddress() = %v for an empty address, want ErrNothingToSweep", err)
+	}
+}
+
+// Assembler that leaves out everything but the coinbases
+type coinbaseOnlyAssembler struct{}
+
+func (coinbaseOnlyAssembler) SelectTransactions(bc *Blockchain, transactions []*Transaction, maxWeight int) ([]*Transaction, []*Transaction) {
+	coinbases, _, candidates := splitCoinbase(transactions)
+	return coinbases, candidates
+}
+
+func TestSweepLeftOutOfBlock(t *testing.T) {
+	bc := newTestChain(t)
+	bc = reopenTestChain(t, bc, WithBlockAssembler(coinbaseOnlyAssembler{}))
+
+	if _, err := SweepAddress("alice", "bob", bc); err != ErrNotMined {
+		t.Errorf("SweepAddress() = %v when the assembler leaves the sweep out, want ErrNotMined", err)
+	}
+	if got := balance(t, bc, "alice"); got != subsidy {
+		t.Errorf("balance of alice = %d after a sweep that wasn't mined, want %d", got, subsidy)
+	}
+
+	mineTestBlock(t, bc, "alice")
+	if _, err := ConsolidateDust("alice", subsidy+1, 2, bc); err != ErrNotMined {
+		t.Errorf("ConsolidateDust() = %v when the assembler leaves it out, want ErrNotMined", err)
+	}
+}
@@ -4,12 +4,35 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/gob"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 const subsidy = 10
 
+// Weight units charged per serialized byte of a regular transaction
+const weightPerByte = 4
+
+// Coinbase transactions only count a nominal weight toward block limits
+const coinbaseWeight = 1
+
+// Most inputs and outputs a single transaction may have
+const (
+	maxTxInputs  = 1000
+	maxTxOutputs = 1000
+)
+
+var (
+	ErrNoInputs       = errors.New("transaction has no inputs")
+	ErrNoOutputs      = errors.New("transaction has no outputs")
+	ErrTooManyInputs  = errors.New("transaction has too many inputs")
+	ErrTooManyOutputs = errors.New("transaction has too many outputs")
+)
+
 type Transaction struct {
 	ID   []byte
 	Vin  []TXInput
@@ -40,15 +63,169 @@ func (tx *Transaction) SetID() {
 	tx.ID = hash[:]
 }
 
-func NewCoinbaseTX(to, data string) *Transaction {
+// Recomputes the ID from the transaction's contents, for checking it
+// against the stored ID
+func (tx Transaction) Hash() []byte {
+	txCopy := tx
+	txCopy.ID = nil
+	txCopy.SetID()
+
+	return txCopy.ID
+}
+
+func (tx Transaction) Serialize() []byte {
+	var encoded bytes.Buffer
+
+	enc := gob.NewEncoder(&encoded)
+	err := enc.Encode(tx)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	return encoded.Bytes()
+}
+
+// Length in bytes of the serialized transaction
+func (tx Transaction) SerializedSize() int {
+	return len(tx.Serialize())
+}
+
+func (tx Transaction) String() string {
+	var lines []string
+
+	lines = append(lines, fmt.Sprintf("Transaction: %x", tx.ID))
+	lines = append(lines, fmt.Sprintf("Size: %d bytes", tx.SerializedSize()))
+	for i, in := range tx.Vin {
+		lines = append(lines, fmt.Sprintf("Input %d: %x:%d %s", i, in.Txid, in.Vout, in.ScriptSig))
+	}
+	for i, out := range tx.Vout {
+		lines = append(lines, fmt.Sprintf("Output %d: %d to %s", i, out.Value, out.ScriptPubKey))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// The cost of including the transaction in a block, measured against maxBlockWeight
+func (tx Transaction) Weight() int {
+	if tx.IsCoinbase() {
+		return coinbaseWeight
+	}
+
+	return tx.SerializedSize() * weightPerByte
+}
+
+// Creates the reward transaction for the block at height. The height is
+// written at the start of the input data so coinbases paying the same
+// address in different blocks still get different IDs.
+func NewCoinbaseTX(to, data string, height int) *Transaction {
 	if data == "" {
 		data = fmt.Sprintf("Reward to %s", to)
 	}
 
-	txin := TXInput{[]byte{}, -1, data}
+	txin := TXInput{[]byte{}, -1, fmt.Sprintf("%d %s", height, data)}
 	txout := TXOutput{subsidy, to}
 	tx := Transaction{nil, []TXInput{txin}, []TXOutput{txout}}
 	tx.SetID()
 
 	return &tx
 }
+
+// Like NewCoinbaseTX, with an extra nonce written between the height and the
+// data. Changing it changes the coinbase ID and so the transactions hash in
+// the block header, which gives a miner a fresh range to search once every
+// nonce has been tried, without touching the other transactions.
+func NewCoinbaseTXExtraNonce(to, data string, height int, extraNonce uint64) *Transaction {
+	if data == "" {
+		data = fmt.Sprintf("Reward to %s", to)
+	}
+
+	return NewCoinbaseTX(to, fmt.Sprintf("%d %s", extraNonce, data), height)
+}
+
+// Adds an output to the coinbase for each address→amount allocation, in
+// address order so the same allocations always give the same transaction
+func (tx *Transaction) addAllocations(allocs map[string]int) {
+	addresses := make([]string, 0, len(allocs))
+	for address := range allocs {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	for _, address := range addresses {
+		tx.Vout = append(tx.Vout, TXOutput{allocs[address], address})
+	}
+
+	tx.ID = nil
+	tx.SetID()
+}
+
+// Returns the transactions with every coinbase rewritten to encode height
+// instead of the height it was built for. The rest of the coinbase data and
+// its outputs are kept.
+func withCoinbaseHeight(transactions []*Transaction, height int) []*Transaction {
+	result := make([]*Transaction, len(transactions))
+
+	for i, tx := range transactions {
+		coinbaseHeight, ok := tx.CoinbaseHeight()
+		if !tx.IsCoinbase() || (ok && coinbaseHeight == height) {
+			result[i] = tx
+			continue
+		}
+
+		data := tx.Vin[0].ScriptSig
+		if ok {
+			_, data, _ = strings.Cut(data, " ")
+		}
+
+		txin := TXInput{[]byte{}, -1, fmt.Sprintf("%d %s", height, data)}
+		rewritten := Transaction{nil, []TXInput{txin}, tx.Vout}
+		rewritten.SetID()
+		result[i] = &rewritten
+	}
+
+	return result
+}
+
+func (tx *Transaction) IsCoinbase() bool {
+	return len(tx.Vin) == 1 && len(tx.Vin[0].Txid) == 0 && tx.Vin[0].Vout == -1
+}
+
+// Rejects transactions with no inputs or outputs, or with more of either
+// than maxTxInputs and maxTxOutputs
+func (tx *Transaction) CheckLimits() error {
+	switch {
+	case len(tx.Vin) == 0:
+		return ErrNoInputs
+	case len(tx.Vout) == 0:
+		return ErrNoOutputs
+	case len(tx.Vin) > maxTxInputs:
+		return ErrTooManyInputs
+	case len(tx.Vout) > maxTxOutputs:
+		return ErrTooManyOutputs
+	}
+
+	return nil
+}
+
+func (in *TXInput) CanUnlockOutputWith(unlockingData string) bool {
+	return in.ScriptSig == unlockingData
+}
+
+func (out *TXOutput) CanBeUnlockedWith(unlockingData string) bool {
+	return out.ScriptPubKey == unlockingData
+}
+
+// Returns the block height encoded in a coinbase's input data
+func (tx *Transaction) CoinbaseHeight() (int, bool) {
+	if !tx.IsCoinbase() {
+		return 0, false
+	}
+
+	prefix, _, _ := strings.Cut(tx.Vin[0].ScriptSig, " ")
+	height, err := strconv.Atoi(prefix)
+	if err != nil {
+		return 0, false
+	}
+
+	return height, true
+}
@@ -0,0 +1,68 @@
+package blockchain
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCoinbaseHeight(t *testing.T) {
+	first := NewCoinbaseTX("alice", "", 1)
+	second := NewCoinbaseTX("alice", "", 2)
+
+	if bytes.Equal(first.ID, second.ID) {
+		t.Error("coinbases at different heights have the same ID")
+	}
+	if height, ok := second.CoinbaseHeight(); !ok || height != 2 {
+		t.Errorf("CoinbaseHeight() = %d, %v, want 2", height, ok)
+	}
+
+	rewritten := withCoinbaseHeight([]*Transaction{first}, 2)[0]
+	if !bytes.Equal(rewritten.ID, second.ID) {
+		t.Errorf("coinbase moved to height 2 has ID %x, want %x", rewritten.ID, second.ID)
+	}
+}
+
+func TestExtraNonceChangesMerkleRoot(t *testing.T) {
+	tx := testTransaction()
+
+	first := newUnsealedBlock([]*Transaction{NewCoinbaseTXExtraNonce("alice", "", 1, 1), tx}, nil, 8)
+	second := newUnsealedBlock([]*Transaction{NewCoinbaseTXExtraNonce("alice", "", 1, 2), tx}, nil, 8)
+
+	if bytes.Equal(first.HashTransactions(), second.HashTransactions()) {
+		t.Error("different extra nonces gave the same transactions hash")
+	}
+
+	coinbase := first.Transactions[0]
+	if height, ok := coinbase.CoinbaseHeight(); !ok || height != 1 {
+		t.Errorf("CoinbaseHeight() = %d, %v with an extra nonce, want 1", height, ok)
+	}
+}
+
+func TestCheckLimits(t *testing.T) {
+	withCounts := func(inputs, outputs int) *Transaction {
+		tx := Transaction{Vin: make([]TXInput, inputs), Vout: make([]TXOutput, outputs)}
+		return &tx
+	}
+
+	tests := []struct {
+		name            string
+		inputs, outputs int
+		want            error
+	}{
+		{"no inputs", 0, 1, ErrNoInputs},
+		{"no outputs", 1, 0, ErrNoOutputs},
+		{"single input and output", 1, 1, nil},
+		{"most inputs", maxTxInputs, 1, nil},
+		{"too many inputs", maxTxInputs + 1, 1, ErrTooManyInputs},
+		{"most outputs", 1, maxTxOutputs, nil},
+		{"too many outputs", 1, maxTxOutputs + 1, ErrTooManyOutputs},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := withCounts(tt.inputs, tt.outputs).CheckLimits(); err != tt.want {
+				t.Errorf("CheckLimits() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
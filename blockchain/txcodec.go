@@ -0,0 +1,141 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+var ErrMalformedTransaction = errors.New("malformed compact transaction encoding")
+
+var ErrNonCanonical = errors.New("transaction is not in canonical compact encoding")
+
+// A compact binary encoding of the transaction, with every count, index and
+// value written as a varint. It is much smaller than gob for typical
+// transactions.
+//
+// It is deliberately not named MarshalBinary: gob prefers a type's
+// BinaryMarshaler over its own encoding, which would change the stored form
+// of every block and the ID of every new transaction. Blocks and IDs stay
+// on gob; this encoding is for callers that ask for it.
+func (tx Transaction) MarshalCompact() []byte {
+	var buf []byte
+
+	buf = appendBytes(buf, tx.ID)
+
+	buf = binary.AppendUvarint(buf, uint64(len(tx.Vin)))
+	for _, in := range tx.Vin {
+		buf = appendBytes(buf, in.Txid)
+		buf = binary.AppendVarint(buf, int64(in.Vout))
+		buf = appendBytes(buf, []byte(in.ScriptSig))
+	}
+
+	buf = binary.AppendUvarint(buf, uint64(len(tx.Vout)))
+	for _, out := range tx.Vout {
+		buf = binary.AppendVarint(buf, int64(out.Value))
+		buf = appendBytes(buf, []byte(out.ScriptPubKey))
+	}
+
+	return buf
+}
+
+// Decodes a transaction written by MarshalCompact, replacing tx's contents.
+// The data has to be exactly what MarshalCompact would write for the
+// transaction, e.g. varints without redundant bytes; otherwise the same
+// transaction could be passed around as different bytes, and ErrNonCanonical
+// is returned.
+func (tx *Transaction) UnmarshalCompact(data []byte) error {
+	r := bytes.NewReader(data)
+	var decoded Transaction
+	var err error
+
+	if decoded.ID, err = readBytes(r); err != nil {
+		return err
+	}
+
+	count, err := readCount(r)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < count; i++ {
+		var in TXInput
+		if in.Txid, err = readBytes(r); err != nil {
+			return err
+		}
+		if in.Vout, err = readInt(r); err != nil {
+			return err
+		}
+		script, err := readBytes(r)
+		if err != nil {
+			return err
+		}
+		in.ScriptSig = string(script)
+		decoded.Vin = append(decoded.Vin, in)
+	}
+
+	if count, err = readCount(r); err != nil {
+		return err
+	}
+	for i := 0; i < count; i++ {
+		var out TXOutput
+		if out.Value, err = readInt(r); err != nil {
+			return err
+		}
+		script, err := readBytes(r)
+		if err != nil {
+			return err
+		}
+		out.ScriptPubKey = string(script)
+		decoded.Vout = append(decoded.Vout, out)
+	}
+
+	if r.Len() != 0 {
+		return ErrMalformedTransaction
+	}
+	if !bytes.Equal(decoded.MarshalCompact(), data) {
+		return ErrNonCanonical
+	}
+
+	*tx = decoded
+	return nil
+}
+
+func appendBytes(buf, b []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(b)))
+	return append(buf, b...)
+}
+
+// Reads a length or element count, which can never exceed the bytes left
+func readCount(r *bytes.Reader) (int, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil || n > uint64(r.Len()) {
+		return 0, ErrMalformedTransaction
+	}
+
+	return int(n), nil
+}
+
+func readInt(r *bytes.Reader) (int, error) {
+	n, err := binary.ReadVarint(r)
+	if err != nil {
+		return 0, ErrMalformedTransaction
+	}
+
+	return int(n), nil
+}
+
+func readBytes(r *bytes.Reader) ([]byte, error) {
+	n, err := readCount(r)
+	if err != nil || n == 0 {
+		// empty slices come back nil, as they do from gob
+		return nil, err
+	}
+
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, ErrMalformedTransaction
+	}
+
+	return b, nil
+}
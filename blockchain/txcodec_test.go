@@ -0,0 +1,73 @@
+package blockchain
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testTransaction() *Transaction {
+	prev := NewCoinbaseTX("alice", "", 1)
+	tx := Transaction{nil, []TXInput{{prev.ID, 0, "alice"}}, []TXOutput{{4, "bob"}, {5, "alice"}}}
+	tx.SetID()
+
+	return &tx
+}
+
+func TestCompactRoundTrip(t *testing.T) {
+	tx := testTransaction()
+	encoded := tx.MarshalCompact()
+
+	var decoded Transaction
+	if err := decoded.UnmarshalCompact(encoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&decoded, tx) {
+		t.Errorf("UnmarshalCompact() = %+v, want %+v", decoded, *tx)
+	}
+
+	if len(encoded) >= len(tx.Serialize()) {
+		t.Errorf("compact encoding is %d bytes, gob %d", len(encoded), len(tx.Serialize()))
+	}
+}
+
+func TestCompactRejectsBadEncodings(t *testing.T) {
+	encoded := testTransaction().MarshalCompact()
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"truncated", encoded[:len(encoded)-1], ErrMalformedTransaction},
+		{"trailing bytes", append(append([]byte{}, encoded...), 0), ErrMalformedTransaction},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tx Transaction
+			if err := tx.UnmarshalCompact(tt.data); err != tt.want {
+				t.Errorf("UnmarshalCompact() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompactRejectsNonCanonical(t *testing.T) {
+	tx := testTransaction()
+	encoded := tx.MarshalCompact()
+
+	var decoded Transaction
+	if err := decoded.UnmarshalCompact(encoded); err != nil {
+		t.Fatalf("UnmarshalCompact() = %v for the canonical encoding", err)
+	}
+
+	// the same transaction with the ID length, 32, written as a two byte
+	// varint; it decodes to the same fields
+	padded := append([]byte{0x80 | encoded[0], 0x00}, encoded[1:]...)
+	if err := decoded.UnmarshalCompact(padded); err != ErrNonCanonical {
+		t.Errorf("UnmarshalCompact() = %v for an overlong varint, want ErrNonCanonical", err)
+	}
+	if !reflect.DeepEqual(&decoded, tx) {
+		t.Error("UnmarshalCompact() changed the transaction on a rejected encoding")
+	}
+}
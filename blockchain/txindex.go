@@ -0,0 +1,127 @@
+package blockchain
+
+import (
+	"errors"
+
+	"github.com/boltdb/bolt"
+)
+
+// Maps each transaction ID to the hash of the block containing it
+const txIndexBucket = "txindex"
+
+var ErrDuplicateTransaction = errors.New("block contains a transaction that is already in the chain")
+
+// Records the block as the location of each of its transactions
+func indexTransactions(tx *bolt.Tx, block *Block) error {
+	b, err := tx.CreateBucketIfNotExists([]byte(txIndexBucket))
+	if err != nil {
+		return err
+	}
+
+	for _, transaction := range block.Transactions {
+		if err := b.Put(transaction.ID, block.Hash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Rejects a block repeating a transaction that is already indexed or appears
+// twice within the block, which would pay its outputs out a second time
+// and move its txindex entry. Coinbase transactions are skipped:
+// checkCoinbaseHeight already makes a new block's coinbase unique by its
+// height, and chains from before heights were encoded hold identical
+// rewards under one ID.
+func checkDuplicateTransactions(tx *bolt.Tx, block *Block) error {
+	b := tx.Bucket([]byte(txIndexBucket))
+	seen := make(map[string]bool)
+
+	for _, transaction := range block.Transactions {
+		if transaction.IsCoinbase() {
+			continue
+		}
+
+		if seen[string(transaction.ID)] {
+			return ErrDuplicateTransaction
+		}
+		seen[string(transaction.ID)] = true
+
+		if b != nil && b.Get(transaction.ID) != nil {
+			return ErrDuplicateTransaction
+		}
+	}
+
+	return nil
+}
+
+// Returns the hash of the block containing the transaction, using the txindex
+func (bc *Blockchain) GetTransactionLocation(txid []byte) ([]byte, error) {
+	var blockHash []byte
+
+	err := bc.Db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(txIndexBucket))
+		if b == nil {
+			return ErrTransactionNotFound
+		}
+
+		location := b.Get(txid)
+		if location == nil {
+			return ErrTransactionNotFound
+		}
+		blockHash = append([]byte{}, location...)
+
+		return nil
+	})
+
+	return blockHash, err
+}
+
+// Rebuilds the txindex from the chain, e.g. for databases created before the
+// index existed. Returns the number of transactions indexed.
+func (bc *Blockchain) BuildTxIndex() (int, error) {
+	count := 0
+
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	err := bc.Db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(txIndexBucket)) != nil {
+			if err := tx.DeleteBucket([]byte(txIndexBucket)); err != nil {
+				return err
+			}
+		}
+
+		index, err := tx.CreateBucket([]byte(txIndexBucket))
+		if err != nil {
+			return err
+		}
+
+		blocks := tx.Bucket([]byte(blocksBucket))
+		hash := bc.tip
+
+		for len(hash) != 0 {
+			block, err := decodeBlockRecord(blocks.Get(hash))
+			if err != nil {
+				return err
+			}
+
+			for _, transaction := range block.Transactions {
+				// walking back from the tip, so the newest block wins for a repeated coinbase
+				if index.Get(transaction.ID) != nil {
+					continue
+				}
+				if err := index.Put(transaction.ID, block.Hash); err != nil {
+					return err
+				}
+				count++
+			}
+
+			hash = block.PrevBlockHash
+		}
+
+		return nil
+	})
+
+	return count, err
+}
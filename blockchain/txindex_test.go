@@ -0,0 +1,64 @@
+package blockchain
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestRepeatedTransactionRejected(t *testing.T) {
+	bc := newTestChain(t)
+	tx := spendTestTX(t, bc, "alice", "bob", 4, 1)
+	mineTestBlock(t, bc, "carol", tx)
+
+	count, _ := bc.GetBlockCount()
+	_, err := bc.MineBlock([]*Transaction{NewCoinbaseTX("carol", "", count), tx})
+	if err != ErrDuplicateTransaction {
+		t.Errorf("MineBlock() = %v for a transaction already in the chain, want ErrDuplicateTransaction", err)
+	}
+
+	_, err = bc.MineBlock([]*Transaction{NewCoinbaseTX("carol", "", count), tx, tx})
+	if err != ErrDuplicateTransaction {
+		t.Errorf("MineBlock() = %v for a transaction repeated in the block, want ErrDuplicateTransaction", err)
+	}
+}
+
+func TestTransactionLocation(t *testing.T) {
+	bc := newTestChain(t)
+	tx := spendTestTX(t, bc, "alice", "bob", 4, 1)
+	block := mineTestBlock(t, bc, "carol", tx)
+	mineTestBlock(t, bc, "carol")
+
+	location, err := bc.GetTransactionLocation(tx.ID)
+	if err != nil || !bytes.Equal(location, block.Hash) {
+		t.Errorf("GetTransactionLocation() = %x, %v, want %x", location, err, block.Hash)
+	}
+
+	// a database from before the index falls back to scanning until it is built
+	err = bc.Db.Update(func(btx *bolt.Tx) error {
+		return btx.DeleteBucket([]byte(txIndexBucket))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bc.GetTransactionLocation(tx.ID); err != ErrTransactionNotFound {
+		t.Errorf("GetTransactionLocation() = %v without an index, want ErrTransactionNotFound", err)
+	}
+	if found, err := bc.FindTransaction(tx.ID); err != nil || !bytes.Equal(found.ID, tx.ID) {
+		t.Errorf("FindTransaction() = %x, %v without an index", found.ID, err)
+	}
+
+	indexed, err := bc.BuildTxIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the coinbase of each of the three blocks, and tx
+	if indexed != 4 {
+		t.Errorf("BuildTxIndex() indexed %d transactions, want 4", indexed)
+	}
+	location, err = bc.GetTransactionLocation(tx.ID)
+	if err != nil || !bytes.Equal(location, block.Hash) {
+		t.Errorf("GetTransactionLocation() = %x, %v after BuildTxIndex, want %x", location, err, block.Hash)
+	}
+}
@@ -0,0 +1,162 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"errors"
+)
+
+var ErrOutputOutOfRange = errors.New("transaction has no output at that index")
+
+// A specific transaction output, identified by its transaction ID and index
+type OutPoint struct {
+	Txid  []byte
+	Index int
+	Value int
+}
+
+// Lists the unspent outputs the given address can unlock, newest first
+func (bc *Blockchain) UTXOsForAddress(address string) ([]OutPoint, error) {
+	return bc.unspentFor(address, 0)
+}
+
+// Lists the unspent outputs of address that are buried deep enough to spend,
+// as set by WithMinConfirmations
+func (bc *Blockchain) SpendableOutputs(address string) ([]OutPoint, error) {
+	minConfirmations := bc.minConfirmations
+	if minConfirmations < 1 {
+		minConfirmations = 1
+	}
+
+	return bc.unspentFor(address, minConfirmations)
+}
+
+// Lists the unspent outputs paid to address, or every unspent output when
+// address is empty, newest first. Outputs of blocks with fewer than
+// minConfirmations confirmations are left out, though their inputs still
+// count as spending.
+func (bc *Blockchain) unspent(address string, minConfirmations int) ([]UnspentOutput, error) {
+	var unspent []UnspentOutput
+	spentTXOs := make(map[string][]int)
+
+	err := bc.walkChain(func(block *Block, depth int) error {
+		confirmed := depth+1 >= minConfirmations
+
+		for _, tx := range block.Transactions {
+			txID := hex.EncodeToString(tx.ID)
+			outputs, inputs := addressActivity(tx, address)
+
+		Outputs:
+			for _, outIdx := range outputs {
+				for _, spentOut := range spentTXOs[txID] {
+					if spentOut == outIdx {
+						continue Outputs
+					}
+				}
+
+				if confirmed {
+					out := tx.Vout[outIdx]
+					unspent = append(unspent, UnspentOutput{OutPoint{tx.ID, outIdx, out.Value}, out.ScriptPubKey})
+				}
+			}
+
+			for _, in := range inputs {
+				inTxID := hex.EncodeToString(in.Txid)
+				spentTXOs[inTxID] = append(spentTXOs[inTxID], in.Vout)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return unspent, nil
+}
+
+func (bc *Blockchain) unspentFor(address string, minConfirmations int) ([]OutPoint, error) {
+	unspent, err := bc.unspent(address, minConfirmations)
+	if err != nil {
+		return nil, err
+	}
+
+	var outPoints []OutPoint
+	for _, out := range unspent {
+		outPoints = append(outPoints, out.OutPoint)
+	}
+
+	return outPoints, nil
+}
+
+// What a scan of the whole chain finds for one address
+type AddressScan struct {
+	// every output ever paid to the address, newest first
+	Received []OutPoint
+	Unspent  []OutPoint
+	Balance  int
+}
+
+// Scans the whole chain for the outputs paid to address and which of them
+// are still unspent, e.g. to discover the history of a newly imported
+// address
+func (bc *Blockchain) Rescan(address string) (*AddressScan, error) {
+	scan := &AddressScan{}
+
+	err := bc.walkChain(func(block *Block, depth int) error {
+		for _, tx := range block.Transactions {
+			outputs, _ := addressActivity(tx, address)
+			for _, outIdx := range outputs {
+				scan.Received = append(scan.Received, OutPoint{tx.ID, outIdx, tx.Vout[outIdx].Value})
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	unspent, err := bc.UTXOsForAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	scan.Unspent = unspent
+
+	for _, out := range unspent {
+		scan.Balance += out.Value
+	}
+
+	return scan, nil
+}
+
+// Resolves a single output, spent or not, by its transaction ID and index
+func (bc *Blockchain) GetOutput(txid []byte, vout int) (TXOutput, error) {
+	if out, ok := bc.prevouts.get(txid, vout); ok {
+		return out, nil
+	}
+
+	tx, err := bc.FindTransaction(txid)
+	if err != nil {
+		return TXOutput{}, err
+	}
+
+	if vout < 0 || vout >= len(tx.Vout) {
+		return TXOutput{}, ErrOutputOutOfRange
+	}
+
+	bc.prevouts.add(txid, vout, tx.Vout[vout])
+
+	return tx.Vout[vout], nil
+}
+
+// An unspent output along with the address it is locked to
+type UnspentOutput struct {
+	OutPoint
+	ScriptPubKey string
+}
+
+// Lists every unspent output on the chain, newest first. There is no UTXO
+// set to read from, so this walks the whole chain.
+func (bc *Blockchain) UnspentOutputs() ([]UnspentOutput, error) {
+	return bc.unspent("", 0)
+}
@@ -0,0 +1,129 @@
+package blockchain
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestGetOutput(t *testing.T) {
+	bc := newTestChain(t)
+	genesis := tipBlock(t, bc)
+	tx := spendTestTX(t, bc, "alice", "bob", 4, 1)
+	mineTestBlock(t, bc, "carol", tx)
+
+	tests := []struct {
+		name string
+		txid []byte
+		vout int
+		want TXOutput
+		err  error
+	}{
+		{"unspent", tx.ID, 1, TXOutput{5, "alice"}, nil},
+		{"spent", genesis.Transactions[0].ID, 0, TXOutput{subsidy, "alice"}, nil},
+		{"out of range", tx.ID, 2, TXOutput{}, ErrOutputOutOfRange},
+		{"negative index", tx.ID, -1, TXOutput{}, ErrOutputOutOfRange},
+		{"unknown transaction", []byte("unknown"), 0, TXOutput{}, ErrTransactionNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := bc.GetOutput(tt.txid, tt.vout)
+			if got != tt.want || err != tt.err {
+				t.Errorf("GetOutput() = %+v, %v, want %+v, %v", got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func TestUnspentOutputsMatchAddressScans(t *testing.T) {
+	bc := newTestChain(t)
+	genesis := tipBlock(t, bc)
+	mineTestBlock(t, bc, "carol", spendTestTX(t, bc, "alice", "bob", 4, 1))
+	mineTestBlock(t, bc, "bob", spendTestTX(t, bc, "bob", "carol", 3, 0))
+
+	unspent, err := bc.UnspentOutputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var perAddress []OutPoint
+	for _, address := range []string{"alice", "bob", "carol"} {
+		outPoints, err := bc.UTXOsForAddress(address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		perAddress = append(perAddress, outPoints...)
+	}
+
+	dumped := make(map[string]bool)
+	for _, out := range unspent {
+		if bytes.Equal(out.Txid, genesis.Transactions[0].ID) {
+			t.Errorf("spent genesis output %d listed as unspent", out.Index)
+		}
+		dumped[fmt.Sprintf("%x:%d:%d", out.Txid, out.Index, out.Value)] = true
+	}
+	for _, out := range perAddress {
+		if !dumped[fmt.Sprintf("%x:%d:%d", out.Txid, out.Index, out.Value)] {
+			t.Errorf("unspent output %x:%d of an address missing from UnspentOutputs()", out.Txid, out.Index)
+		}
+	}
+	if len(unspent) != len(perAddress) {
+		t.Errorf("UnspentOutputs() lists %d outputs, the addresses have %d", len(unspent), len(perAddress))
+	}
+}
+
+func TestSpendableOutputsMinConfirmations(t *testing.T) {
+	bc := newTestChain(t, WithMinConfirmations(3))
+	older := mineTestBlock(t, bc, "bob")
+	newer := mineTestBlock(t, bc, "bob")
+	mineTestBlock(t, bc, "carol")
+
+	// older is now three blocks deep, newer two
+	spendable, err := bc.SpendableOutputs("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spendable) != 1 || !bytes.Equal(spendable[0].Txid, older.Transactions[0].ID) {
+		t.Errorf("SpendableOutputs() = %+v, want only the output of %x", spendable, older.Hash)
+	}
+	for _, out := range spendable {
+		if bytes.Equal(out.Txid, newer.Transactions[0].ID) {
+			t.Error("output with two confirmations is spendable")
+		}
+	}
+
+	bc = reopenTestChain(t, bc)
+	if spendable, _ := bc.SpendableOutputs("bob"); len(spendable) != 2 {
+		t.Errorf("SpendableOutputs() = %d outputs by default, want both", len(spendable))
+	}
+}
+
+func TestRescanFindsPastPayments(t *testing.T) {
+	bc := newTestChain(t)
+	paid := spendTestTX(t, bc, "alice", "bob", 4, 1)
+	reward := mineTestBlock(t, bc, "bob", paid)
+	mineTestBlock(t, bc, "carol", spendTestTX(t, bc, "bob", "carol", 12, 0))
+
+	// bob received 4 and a block reward, then spent both; the change is
+	// the only output left
+	scan, err := bc.Rescan("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scan.Received) != 3 {
+		t.Errorf("Rescan() found %d received outputs, want 3", len(scan.Received))
+	}
+	for _, txid := range [][]byte{paid.ID, reward.Transactions[0].ID} {
+		found := false
+		for _, out := range scan.Received {
+			found = found || bytes.Equal(out.Txid, txid)
+		}
+		if !found {
+			t.Errorf("Rescan() missed the output of %x", txid)
+		}
+	}
+	if len(scan.Unspent) != 1 || scan.Balance != 4+subsidy-12 {
+		t.Errorf("Rescan() = %d unspent outputs worth %d, want the change of %d", len(scan.Unspent), scan.Balance, 4+subsidy-12)
+	}
+}
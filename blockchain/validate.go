@@ -0,0 +1,196 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"sync"
+)
+
+var (
+	ErrBlockTooHeavy    = errors.New("block weight exceeds the maximum block weight")
+	ErrCoinbaseHeight   = errors.New("coinbase does not encode the block's height")
+	ErrEmptyBlock       = errors.New("block has no coinbase transaction")
+	ErrHashMismatch     = errors.New("block hash does not match its contents")
+	ErrInvalidPoW       = errors.New("block hash does not meet the proof of work target")
+	ErrInvalidSeal      = errors.New("block seal is not accepted by the chain's consensus")
+	ErrPrevHashMismatch = errors.New("block does not link to the previous block")
+	ErrTxIDMismatch     = errors.New("transaction ID does not match its contents")
+)
+
+// Checks that the block is correctly sealed by proof of work and follows
+// prev. A nil prev means the block must be a genesis block.
+func (b *Block) Validate(prev *Block) error {
+	if err := b.verifyPoW(); err != nil {
+		return err
+	}
+
+	return b.checkLink(prev)
+}
+
+// Checks the hash against the block's own target: Bits when set, which
+// may be finer-grained than whole target bits, otherwise Difficulty.
+// Whether that target is hard enough for the chain is up to checkTarget.
+func (b *Block) verifyPoW() error {
+	pow := NewProofOfWork(b)
+
+	hash := sha256.Sum256(pow.prepareData(b.Nonce))
+	if !bytes.Equal(hash[:], b.Hash) {
+		return ErrHashMismatch
+	}
+
+	if !pow.Validate() {
+		return ErrInvalidPoW
+	}
+
+	return nil
+}
+
+func (b *Block) checkLink(prev *Block) error {
+	if prev == nil {
+		if len(b.PrevBlockHash) != 0 {
+			return ErrPrevHashMismatch
+		}
+	} else if !bytes.Equal(b.PrevBlockHash, prev.Hash) {
+		return ErrPrevHashMismatch
+	}
+
+	return nil
+}
+
+// Checks that every transaction ID is the hash of its transaction
+func (b *Block) checkTransactionIDs() error {
+	for _, tx := range b.Transactions {
+		if !bytes.Equal(tx.Hash(), tx.ID) {
+			return ErrTxIDMismatch
+		}
+	}
+
+	return nil
+}
+
+// Validates a run of consecutive blocks, as received during a sync. Proof of
+// work and transaction IDs are checked for each block on its own across
+// workers goroutines; links between the blocks are checked serially after.
+// The first block's link to the chain is left to AddBlock. The result holds
+// the first error found for each block, nil if it is valid.
+func ValidateBlocks(blocks []*Block, workers int) []error {
+	if workers < 1 {
+		workers = 1
+	}
+
+	errs := make([]error, len(blocks))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range indexes {
+				if err := blocks[i].verifyPoW(); err != nil {
+					errs[i] = err
+					continue
+				}
+				errs[i] = blocks[i].checkTransactionIDs()
+			}
+		}()
+	}
+
+	for i := range blocks {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for i := 1; i < len(blocks); i++ {
+		if errs[i] == nil {
+			errs[i] = blocks[i].checkLink(blocks[i-1])
+		}
+	}
+
+	return errs
+}
+
+func checkTransactionLimits(block *Block) error {
+	for _, tx := range block.Transactions {
+		if err := tx.CheckLimits(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Checks that the block's transactions fit within maxBlockWeight, as
+// MineBlock's assembler makes sure of for blocks mined here
+func checkBlockWeight(block *Block) error {
+	weight := 0
+	for _, tx := range block.Transactions {
+		weight += tx.Weight()
+	}
+
+	if weight > maxBlockWeight {
+		return ErrBlockTooHeavy
+	}
+
+	return nil
+}
+
+// Checks that the block was sealed at the chain's difficulty. A block with
+// Bits may be sealed at any target at least as hard as the one for
+// difficulty; Difficulty is then only the whole number of bits it is shown
+// as, and must still match.
+func checkTarget(block *Block, difficulty int) error {
+	if block.Difficulty != difficulty {
+		return ErrWrongDifficulty
+	}
+	if block.Bits != 0 && CompactToTarget(block.Bits).Cmp(targetForBits(difficulty)) > 0 {
+		return ErrWrongDifficulty
+	}
+
+	return nil
+}
+
+// A block needs no transactions besides its coinbase, but it does need the
+// coinbase
+func checkHasCoinbase(block *Block) error {
+	for _, tx := range block.Transactions {
+		if tx.IsCoinbase() {
+			return nil
+		}
+	}
+
+	return ErrEmptyBlock
+}
+
+// Checks that every coinbase in a block at height encodes that height
+func checkCoinbaseHeight(block *Block, height int) error {
+	for _, tx := range block.Transactions {
+		if !tx.IsCoinbase() {
+			continue
+		}
+
+		if coinbaseHeight, ok := tx.CoinbaseHeight(); !ok || coinbaseHeight != height {
+			return ErrCoinbaseHeight
+		}
+	}
+
+	return nil
+}
+
+// Validates a block against the chain's own consensus rather than assuming
+// proof of work
+func (bc *Blockchain) validateBlock(block, prev *Block) error {
+	if _, ok := bc.consensus.(PoWConsensus); ok {
+		// keeps the specific proof of work errors
+		return block.Validate(prev)
+	}
+
+	if !bc.consensus.Verify(block) {
+		return ErrInvalidSeal
+	}
+
+	return block.checkLink(prev)
+}
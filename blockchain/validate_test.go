@@ -0,0 +1,278 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+// Mines a block at 8 target bits on top of prev, nil for a genesis block
+func sealedTestBlock(t *testing.T, prev *Block) *Block {
+	t.Helper()
+
+	var prevHash []byte
+	height := 0
+	if prev != nil {
+		prevHash = prev.Hash
+		height = 1
+	}
+
+	block := newUnsealedBlock([]*Transaction{NewCoinbaseTX("alice", "", height)}, prevHash, 8)
+	if err := (PoWConsensus{}).Seal(block); err != nil {
+		t.Fatal(err)
+	}
+
+	return block
+}
+
+func TestValidateAcceptsSealedBlocks(t *testing.T) {
+	SetLogger(NopLogger)
+	genesis := sealedTestBlock(t, nil)
+	next := sealedTestBlock(t, genesis)
+
+	if err := genesis.Validate(nil); err != nil {
+		t.Errorf("genesis.Validate(nil) = %v", err)
+	}
+	if err := next.Validate(genesis); err != nil {
+		t.Errorf("next.Validate(genesis) = %v", err)
+	}
+}
+
+func TestValidateFailures(t *testing.T) {
+	SetLogger(NopLogger)
+	genesis := sealedTestBlock(t, nil)
+	other := &Block{Hash: []byte("some other block")}
+
+	tests := []struct {
+		name   string
+		tamper func(b *Block)
+		prev   *Block
+		want   error
+	}{
+		{"changed contents", func(b *Block) { b.Timestamp++ }, genesis, ErrHashMismatch},
+		{"changed hash", func(b *Block) { b.Hash[0] ^= 0xff }, genesis, ErrHashMismatch},
+		{"hash above target", rehashAboveTarget, genesis, ErrInvalidPoW},
+		// Bits is part of the hashed data, so it can't be eased after sealing
+		{"easier bits", func(b *Block) { b.Bits = TargetToCompact(targetForBits(4)) }, genesis, ErrHashMismatch},
+		{"wrong previous block", func(b *Block) {}, other, ErrPrevHashMismatch},
+		{"genesis with a previous block", func(b *Block) {}, nil, ErrPrevHashMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			block := sealedTestBlock(t, genesis)
+			tt.tamper(block)
+
+			if err := block.Validate(tt.prev); err != tt.want {
+				t.Errorf("Validate() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// Moves the block to the next nonce whose hash misses the target, keeping
+// the hash consistent with the contents
+func rehashAboveTarget(b *Block) {
+	pow := NewProofOfWork(b)
+
+	for b.Nonce++; ; b.Nonce++ {
+		hash := sha256.Sum256(pow.prepareData(b.Nonce))
+		b.Hash = hash[:]
+		if !pow.Validate() {
+			return
+		}
+	}
+}
+
+func TestAddBlockValidates(t *testing.T) {
+	bc := newTestChain(t)
+
+	block := newUnsealedBlock([]*Transaction{NewCoinbaseTX("bob", "", 1)}, bc.GetBestBlockHash(), 8)
+	if err := bc.seal(block); err != nil {
+		t.Fatal(err)
+	}
+	rehashAboveTarget(block)
+
+	if err := bc.AddBlock(block); err != ErrInvalidPoW {
+		t.Errorf("AddBlock() = %v, want ErrInvalidPoW", err)
+	}
+	if count, _ := bc.GetBlockCount(); count != 1 {
+		t.Errorf("GetBlockCount() = %d after a rejected block, want 1", count)
+	}
+}
+
+func TestAddBlockRejectsWrongCoinbaseHeight(t *testing.T) {
+	bc := newTestChain(t)
+	genesis := tipBlock(t, bc)
+
+	block := newUnsealedBlock([]*Transaction{NewCoinbaseTX("bob", "", 5)}, genesis.Hash, 8)
+	block.Timestamp = genesis.Timestamp + 1
+	if err := bc.seal(block); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bc.AddBlock(block); err != ErrCoinbaseHeight {
+		t.Errorf("AddBlock() = %v for a coinbase at the wrong height, want ErrCoinbaseHeight", err)
+	}
+}
+
+// Mines n blocks after genesis and returns them oldest first
+func mineTestRun(t testing.TB, n int) []*Block {
+	SetLogger(NopLogger)
+
+	var blocks []*Block
+	var prev *Block
+	for i := 0; i < n; i++ {
+		var prevHash []byte
+		if prev != nil {
+			prevHash = prev.Hash
+		}
+
+		block := newUnsealedBlock([]*Transaction{NewCoinbaseTX("alice", "", i)}, prevHash, 8)
+		if err := (PoWConsensus{}).Seal(block); err != nil {
+			t.Fatal(err)
+		}
+		blocks = append(blocks, block)
+		prev = block
+	}
+
+	return blocks
+}
+
+func TestValidateBlocksMatchesSerial(t *testing.T) {
+	blocks := mineTestRun(t, 20)
+	blocks[7].Timestamp++
+	blocks[12].Transactions[0].Vout[0].Value = 1000
+	blocks[15].PrevBlockHash = blocks[3].Hash
+
+	serial := make([]error, len(blocks))
+	for i, block := range blocks {
+		var prev *Block
+		if i > 0 {
+			prev = blocks[i-1]
+		}
+		if serial[i] = block.Validate(prev); serial[i] == nil {
+			serial[i] = block.checkTransactionIDs()
+		}
+	}
+	// the first block's link to the chain is left to AddBlock
+	serial[0] = blocks[0].verifyPoW()
+
+	parallel := ValidateBlocks(blocks, 4)
+	for i := range blocks {
+		if parallel[i] != serial[i] {
+			t.Errorf("block %d: ValidateBlocks() = %v, serial validation %v", i, parallel[i], serial[i])
+		}
+	}
+	for _, i := range []int{7, 12, 15} {
+		if parallel[i] == nil {
+			t.Errorf("block %d: tampered block not flagged", i)
+		}
+	}
+}
+
+func BenchmarkValidateBlocks(b *testing.B) {
+	blocks := mineTestRun(b, 200)
+
+	for _, workers := range []int{1, 4} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				ValidateBlocks(blocks, workers)
+			}
+		})
+	}
+}
+
+func TestBlocksNeedOnlyACoinbase(t *testing.T) {
+	bc := newTestChain(t)
+	mineTestBlock(t, bc, "bob")
+
+	if count, _ := bc.GetBlockCount(); count != 2 {
+		t.Errorf("GetBlockCount() = %d after a coinbase-only block, want 2", count)
+	}
+
+	tip := tipBlock(t, bc)
+	empty := newUnsealedBlock(nil, tip.Hash, 8)
+	empty.Timestamp = tip.Timestamp + 1
+	if err := bc.seal(empty); err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.AddBlock(empty); err != ErrEmptyBlock {
+		t.Errorf("AddBlock() = %v for a block without a coinbase, want ErrEmptyBlock", err)
+	}
+}
+
+func TestAddBlockChecksTransactionLimits(t *testing.T) {
+	bc := newTestChain(t)
+	tip := tipBlock(t, bc)
+
+	noOutputs := Transaction{nil, []TXInput{{tip.Transactions[0].ID, 0, "alice"}}, nil}
+	noOutputs.SetID()
+
+	block := newUnsealedBlock([]*Transaction{NewCoinbaseTX("bob", "", 1), &noOutputs}, tip.Hash, 8)
+	block.Timestamp = tip.Timestamp + 1
+	if err := bc.seal(block); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bc.AddBlock(block); err != ErrNoOutputs {
+		t.Errorf("AddBlock() = %v for a transaction without outputs, want ErrNoOutputs", err)
+	}
+}
+
+func TestAddBlockRejectsOverweight(t *testing.T) {
+	bc, txs := heavyTestTXs(t)
+	genesis := tipBlock(t, bc)
+
+	block := newUnsealedBlock(append([]*Transaction{NewCoinbaseTX("alice", "", 1)}, txs...), genesis.Hash, bc.Difficulty())
+	block.Timestamp = genesis.Timestamp + 1
+	if err := bc.seal(block); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bc.AddBlock(block); err != ErrBlockTooHeavy {
+		t.Errorf("AddBlock() = %v for a block over maxBlockWeight, want ErrBlockTooHeavy", err)
+	}
+	if !bytes.Equal(bc.GetBestBlockHash(), genesis.Hash) {
+		t.Error("tip moved to an overweight block")
+	}
+}
+
+func TestFinerGrainedTargets(t *testing.T) {
+	bc := newTestChain(t)
+	genesis := tipBlock(t, bc)
+
+	sealAt := func(target *big.Int) *Block {
+		block := newUnsealedBlock([]*Transaction{NewCoinbaseTX("bob", "", 1)}, genesis.Hash, bc.Difficulty())
+		block.Timestamp = genesis.Timestamp + 1
+		block.Bits = TargetToCompact(target)
+		if err := bc.seal(block); err != nil {
+			t.Fatal(err)
+		}
+		return block
+	}
+	chainTarget := targetForBits(bc.Difficulty())
+
+	// twice as easy as the chain allows, though validly sealed at that target
+	easier := sealAt(new(big.Int).Lsh(chainTarget, 1))
+	if err := easier.Validate(genesis); err != nil {
+		t.Errorf("Validate() = %v for a block sealed at its own easier target", err)
+	}
+	if err := bc.AddBlock(easier); err != ErrWrongDifficulty {
+		t.Errorf("AddBlock() = %v for a target easier than the chain's, want ErrWrongDifficulty", err)
+	}
+
+	// between the chain's bits and the next, which whole bits can't express
+	finer := sealAt(new(big.Int).Div(new(big.Int).Mul(chainTarget, big.NewInt(3)), big.NewInt(4)))
+	if err := finer.Validate(genesis); err != nil {
+		t.Errorf("Validate() = %v for a block sealed at a finer-grained target", err)
+	}
+	if err := bc.AddBlock(finer); err != nil {
+		t.Errorf("AddBlock() = %v for a target harder than the chain's", err)
+	}
+	if got := NewProofOfWork(finer).Difficulty() / NewProofOfWork(genesis).Difficulty(); got < 1.33 || got > 1.34 {
+		t.Errorf("finer-grained target is %v times as hard as the chain's, want 4/3", got)
+	}
+}
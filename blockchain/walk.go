@@ -0,0 +1,48 @@
+package blockchain
+
+// Calls fn for every block from the tip back to genesis, along with its
+// depth, the tip being 0
+func (bc *Blockchain) walkChain(fn func(block *Block, depth int) error) error {
+	bci := bc.Iterator()
+
+	for depth := 0; ; depth++ {
+		block, err := bci.Next()
+		if err != nil {
+			return err
+		}
+
+		if err := fn(block, depth); err != nil {
+			return err
+		}
+
+		if len(block.PrevBlockHash) == 0 {
+			return nil
+		}
+	}
+}
+
+// Returns the indexes of the outputs of tx paid to address and the inputs
+// of tx spending from it. An empty address matches every output and input.
+// Coinbase inputs don't spend anything and are never returned.
+func addressActivity(tx *Transaction, address string) ([]int, []TXInput) {
+	var outputs []int
+	var inputs []TXInput
+
+	for outIdx, out := range tx.Vout {
+		if address == "" || out.CanBeUnlockedWith(address) {
+			outputs = append(outputs, outIdx)
+		}
+	}
+
+	if tx.IsCoinbase() {
+		return outputs, nil
+	}
+
+	for _, in := range tx.Vin {
+		if address == "" || in.CanUnlockOutputWith(address) {
+			inputs = append(inputs, in)
+		}
+	}
+
+	return outputs, inputs
+}
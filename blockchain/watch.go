@@ -0,0 +1,76 @@
+package blockchain
+
+type addressWatch struct {
+	address string
+	ch      chan int
+}
+
+// Subscribes to balance changes of an address. Every time a new block pays
+// to or spends from the address, its new balance is sent on the channel.
+// Only the latest balance is kept if the receiver falls behind. The
+// returned function unsubscribes and closes the channel.
+func (bc *Blockchain) WatchAddress(address string) (<-chan int, func()) {
+	bc.watchMu.Lock()
+	defer bc.watchMu.Unlock()
+
+	if bc.watchers == nil {
+		bc.watchers = make(map[int]*addressWatch)
+	}
+
+	id := bc.nextWatch
+	bc.nextWatch++
+
+	watch := &addressWatch{address, make(chan int, 1)}
+	bc.watchers[id] = watch
+
+	unsubscribe := func() {
+		bc.watchMu.Lock()
+		defer bc.watchMu.Unlock()
+
+		if _, ok := bc.watchers[id]; ok {
+			delete(bc.watchers, id)
+			close(watch.ch)
+		}
+	}
+
+	return watch.ch, unsubscribe
+}
+
+func (bc *Blockchain) notifyWatchers(block *Block) {
+	bc.watchMu.Lock()
+	defer bc.watchMu.Unlock()
+
+	for _, watch := range bc.watchers {
+		if !blockAffectsAddress(block, watch.address) {
+			continue
+		}
+
+		outPoints, err := bc.UTXOsForAddress(watch.address)
+		if err != nil {
+			logger.Errorf("Computing balance of '%s' for watchers: %s", watch.address, err)
+			continue
+		}
+
+		balance := 0
+		for _, out := range outPoints {
+			balance += out.Value
+		}
+
+		// replace a balance the receiver hasn't picked up yet
+		select {
+		case <-watch.ch:
+		default:
+		}
+		watch.ch <- balance
+	}
+}
+
+func blockAffectsAddress(block *Block, address string) bool {
+	for _, tx := range block.Transactions {
+		if outputs, inputs := addressActivity(tx, address); len(outputs) > 0 || len(inputs) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
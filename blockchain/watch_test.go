@@ -0,0 +1,28 @@
+package blockchain
+
+import "testing"
+
+func TestWatchAddress(t *testing.T) {
+	bc := newTestChain(t)
+	balances, unsubscribe := bc.WatchAddress("bob")
+
+	mineTestBlock(t, bc, "carol", spendTestTX(t, bc, "alice", "bob", 4, 1))
+	if got := <-balances; got != 4 {
+		t.Errorf("watched balance = %d after funding bob, want 4", got)
+	}
+
+	mineTestBlock(t, bc, "carol")
+	select {
+	case got := <-balances:
+		t.Errorf("watched balance %d sent for a block not affecting bob", got)
+	default:
+	}
+
+	unsubscribe()
+	if _, ok := <-balances; ok {
+		t.Error("channel still open after unsubscribing")
+	}
+	// funding bob again must not send on the closed channel
+	mineTestBlock(t, bc, "bob")
+	unsubscribe()
+}
@@ -0,0 +1,147 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// How often a webhook POST is tried before giving up, and the delay before
+// the first retry, doubled for each one after it
+const (
+	webhookMaxAttempts = 5
+	webhookRetryDelay  = time.Second
+)
+
+// Gives up on a webhook POST that hasn't been answered within its Timeout,
+// so a hung endpoint costs a retry instead of the delivery goroutine
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+type webhook struct {
+	txid          []byte
+	confirmations int
+	url           string
+}
+
+// Body of the POST sent to a webhook
+type webhookPayload struct {
+	Txid          string `json:"txid"`
+	Confirmations int    `json:"confirmations"`
+}
+
+// Registers url to receive a JSON POST once the transaction has at least
+// the given number of confirmations. It is checked each time a block is
+// added, using the txindex, and fires only once. Failed POSTs are retried
+// with backoff up to webhookMaxAttempts times.
+func (bc *Blockchain) RegisterWebhook(txid []byte, confirmations int, url string) {
+	bc.watchMu.Lock()
+	defer bc.watchMu.Unlock()
+
+	bc.webhooks = append(bc.webhooks, &webhook{append([]byte{}, txid...), confirmations, url})
+}
+
+// Fires the webhooks whose transactions have enough confirmations with the
+// tip at tipHeight. Confirmations are looked up without holding watchMu;
+// a hook is only fired by whoever removes it from the list, so it fires
+// once even if blocks are added concurrently.
+func (bc *Blockchain) notifyWebhooks(tipHeight int) {
+	bc.watchMu.Lock()
+	hooks := append([]*webhook{}, bc.webhooks...)
+	bc.watchMu.Unlock()
+
+	ready := make(map[*webhook]int)
+	for _, hook := range hooks {
+		confirmations, err := bc.confirmationsAt(hook.txid, tipHeight)
+		if err != nil || confirmations < hook.confirmations {
+			continue
+		}
+		ready[hook] = confirmations
+	}
+	if len(ready) == 0 {
+		return
+	}
+
+	bc.watchMu.Lock()
+	defer bc.watchMu.Unlock()
+
+	pending := bc.webhooks[:0]
+	for _, hook := range bc.webhooks {
+		confirmations, ok := ready[hook]
+		if !ok {
+			pending = append(pending, hook)
+			continue
+		}
+
+		go hook.post(confirmations)
+	}
+	bc.webhooks = pending
+}
+
+// Confirmations of a transaction with the tip at tipHeight, from the
+// txindex and the height encoded in the containing block's coinbase rather
+// than a walk down the chain. Blocks from before coinbases carried their
+// height fall back to Confirmations.
+func (bc *Blockchain) confirmationsAt(txid []byte, tipHeight int) (int, error) {
+	location, err := bc.GetTransactionLocation(txid)
+	if err != nil {
+		return 0, err
+	}
+
+	block, err := bc.GetBlock(location)
+	if err != nil {
+		return 0, err
+	}
+
+	height, ok := block.Transactions[0].CoinbaseHeight()
+	if !ok {
+		return bc.Confirmations(txid)
+	}
+
+	// the index may point at a block that is no longer on the main chain
+	if onChain, err := bc.GetBlockHash(height); err != nil || !bytes.Equal(onChain, location) {
+		return 0, ErrTransactionNotFound
+	}
+
+	return tipHeight - height + 1, nil
+}
+
+func (hook *webhook) post(confirmations int) {
+	body, err := json.Marshal(webhookPayload{hex.EncodeToString(hook.txid), confirmations})
+	if err != nil {
+		logger.Errorf("Encoding webhook for %x: %s", hook.txid, err)
+		return
+	}
+
+	delay := webhookRetryDelay
+	for attempt := 1; ; attempt++ {
+		err = postJSON(hook.url, body)
+		if err == nil {
+			return
+		}
+		if attempt == webhookMaxAttempts {
+			break
+		}
+
+		time.Sleep(delay)
+		delay *= 2
+	}
+
+	logger.Errorf("Webhook %s for %x failed after %d attempts: %s", hook.url, hook.txid, webhookMaxAttempts, err)
+}
+
+func postJSON(url string, body []byte) error {
+	resp, err := webhookClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return nil
+}
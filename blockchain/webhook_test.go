@@ -0,0 +1,82 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebhookFiresAtConfirmationDepth(t *testing.T) {
+	payloads := make(chan webhookPayload, 10)
+	failed := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the first POST fails so the hook has to retry
+		if !failed {
+			failed = true
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		var payload webhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decoding webhook body: %s", err)
+		}
+		payloads <- payload
+	}))
+	defer server.Close()
+
+	bc := newTestChain(t)
+	tx := spendTestTX(t, bc, "alice", "bob", 4, 1)
+	bc.RegisterWebhook(tx.ID, 3, server.URL)
+
+	mineTestBlock(t, bc, "carol", tx)
+	mineTestBlock(t, bc, "carol")
+	select {
+	case payload := <-payloads:
+		t.Fatalf("webhook fired at %d confirmations, want 3", payload.Confirmations)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	mineTestBlock(t, bc, "carol")
+	select {
+	case payload := <-payloads:
+		if payload.Txid != hex.EncodeToString(tx.ID) || payload.Confirmations != 3 {
+			t.Errorf("webhook payload %+v, want txid %x with 3 confirmations", payload, tx.ID)
+		}
+	case <-time.After(5 * webhookRetryDelay):
+		t.Fatal("webhook didn't fire at 3 confirmations")
+	}
+
+	mineTestBlock(t, bc, "carol")
+	select {
+	case payload := <-payloads:
+		t.Errorf("webhook fired again at %d confirmations", payload.Confirmations)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestWebhookPostTimesOut(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := webhookClient
+	webhookClient = &http.Client{Timeout: 50 * time.Millisecond}
+	defer func() {
+		webhookClient = client
+	}()
+
+	start := time.Now()
+	if err := postJSON(server.URL, []byte("{}")); err == nil {
+		t.Error("postJSON() succeeded against an endpoint that never answers")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("postJSON() took %s to give up on a hung endpoint", elapsed)
+	}
+}
@@ -1,91 +1,821 @@
 package cli
 
 import (
+	"bytes"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"go-blockchain/blockchain"
+	"io"
 	"log"
 	"os"
 	"strconv"
+	"strings"
 )
 
+// Size at which the -auditlog file is rotated
+const auditLogMaxSize = 10 << 20
+
 type CLI struct {
+	// where command output goes, os.Stdout if nil
+	Out io.Writer
+
+	options []blockchain.Option
 }
 
 func (cli *CLI) Run() {
 	//cli.validateArgs()
 
+	if cli.Out == nil {
+		cli.Out = os.Stdout
+	}
+
 	addBlockCmd := flag.NewFlagSet("addblock", flag.ExitOnError)
 	printChainCmd := flag.NewFlagSet("printchain", flag.ExitOnError)
 	createBlockchainCmd := flag.NewFlagSet("createblockchain", flag.ExitOnError)
+	repairCmd := flag.NewFlagSet("repair", flag.ExitOnError)
+	listUnspentCmd := flag.NewFlagSet("listunspent", flag.ExitOnError)
+	estimateFeeCmd := flag.NewFlagSet("estimatefee", flag.ExitOnError)
+	chainInfoCmd := flag.NewFlagSet("chaininfo", flag.ExitOnError)
+	getTxCmd := flag.NewFlagSet("gettx", flag.ExitOnError)
+	sweepCmd := flag.NewFlagSet("sweep", flag.ExitOnError)
+	buildTxIndexCmd := flag.NewFlagSet("buildtxindex", flag.ExitOnError)
+	getDifficultyCmd := flag.NewFlagSet("getdifficulty", flag.ExitOnError)
+	setDifficultyCmd := flag.NewFlagSet("setdifficulty", flag.ExitOnError)
+	merkleRootCmd := flag.NewFlagSet("merkleroot", flag.ExitOnError)
+	dumpUTXOsCmd := flag.NewFlagSet("dumputxos", flag.ExitOnError)
+	blockStatsCmd := flag.NewFlagSet("blockstats", flag.ExitOnError)
+	fingerprintCmd := flag.NewFlagSet("fingerprint", flag.ExitOnError)
+	getBlockHashCmd := flag.NewFlagSet("getblockhash", flag.ExitOnError)
+	getRawBlockCmd := flag.NewFlagSet("getrawblock", flag.ExitOnError)
+	decodeRawBlockCmd := flag.NewFlagSet("decoderawblock", flag.ExitOnError)
+	rescanCmd := flag.NewFlagSet("rescan", flag.ExitOnError)
+	resetCmd := flag.NewFlagSet("reset", flag.ExitOnError)
+	dbDumpCmd := flag.NewFlagSet("dbdump", flag.ExitOnError)
+	consolidateCmd := flag.NewFlagSet("consolidate", flag.ExitOnError)
+	exportHistoryCmd := flag.NewFlagSet("exporthistory", flag.ExitOnError)
 
 	addBlockData := addBlockCmd.String("data", "", "Block data")
 	createBlockchainAddress := createBlockchainCmd.String("address", "", "The address to send genesis block reward to")
+	createBlockchainGenesisData := createBlockchainCmd.String("genesis-data", "", "Custom coinbase data for the genesis block")
+	createBlockchainPremine := createBlockchainCmd.String("premine", "", "Genesis balances as address=amount pairs separated by commas")
+	createBlockchainFormat := createBlockchainCmd.String("format", "gob", "Block storage format: gob, json or compact")
+	listUnspentAddress := listUnspentCmd.String("address", "", "The address to list unspent outputs for")
+	estimateFeeBlocks := estimateFeeCmd.Int("blocks", 6, "Number of blocks the transaction should confirm within")
+	getTxID := getTxCmd.String("id", "", "Hex ID of the transaction")
+	sweepFrom := sweepCmd.String("from", "", "The address to move all funds from")
+	sweepTo := sweepCmd.String("to", "", "The address to move all funds to")
+	sweepMinConf := sweepCmd.Int("minconf", 1, "Only spend outputs with at least this many confirmations")
+	setDifficultyBits := setDifficultyCmd.Int("bits", 0, "Target bits for subsequent blocks, 1 to 32")
+	merkleRootHash := merkleRootCmd.String("hash", "", "Hex hash of the block")
+	blockStatsHash := blockStatsCmd.String("hash", "", "Hex hash of the block")
+	getBlockHashHeight := getBlockHashCmd.Int("height", -1, "Height of the block, genesis being 0")
+	getRawBlockHash := getRawBlockCmd.String("hash", "", "Hex hash of the block")
+	getRawBlockJSON := getRawBlockCmd.Bool("json", false, "Print the block as JSON instead of hex")
+	decodeRawBlockHex := decodeRawBlockCmd.String("hex", "", "Hex encoded block, as printed by getrawblock")
+	rescanAddress := rescanCmd.String("address", "", "The address to scan the chain for")
+	resetAddress := resetCmd.String("address", "", "The address to send the new genesis block reward to")
+	resetForce := resetCmd.Bool("force", false, "Really delete the existing chain")
+	dbDumpBucket := dbDumpCmd.String("bucket", "", "Name of the database bucket, e.g. Blocks")
+	consolidateAddress := consolidateCmd.String("address", "", "The address to consolidate dust outputs of")
+	consolidateThreshold := consolidateCmd.Int("threshold", blockchain.DefaultDustThreshold, "Consolidate outputs worth less than this")
+	consolidateMin := consolidateCmd.Int("min", blockchain.DefaultMinDustOutputs, "Do nothing if there are fewer dust outputs than this")
+	exportHistoryAddress := exportHistoryCmd.String("address", "", "The address to export the transactions of")
+	exportHistoryFile := exportHistoryCmd.String("file", "", "CSV file to write")
+
+	commands := []command{
+		{addBlockCmd, "Add a block with the given data (not implemented)", func() {
+			if *addBlockData == "" {
+				addBlockCmd.Usage()
+				os.Exit(1)
+			}
+			cli.addBlock(*addBlockData)
+		}},
+		{printChainCmd, "Print every block from the tip back to genesis", cli.printChain},
+		{createBlockchainCmd, "Create a new chain, paying the genesis reward to an address", func() {
+			if *createBlockchainAddress == "" {
+				createBlockchainCmd.Usage()
+				os.Exit(1)
+			}
+			cli.createBlockchain(*createBlockchainAddress, *createBlockchainGenesisData, *createBlockchainPremine, *createBlockchainFormat)
+		}},
+		{repairCmd, "Reset the tip to the best valid block", cli.repair},
+		{listUnspentCmd, "List the unspent outputs of an address", func() {
+			if *listUnspentAddress == "" {
+				listUnspentCmd.Usage()
+				os.Exit(1)
+			}
+			cli.listUnspent(*listUnspentAddress)
+		}},
+		{estimateFeeCmd, "Estimate the fee rate needed to confirm within a number of blocks", func() {
+			if *estimateFeeBlocks < 1 {
+				estimateFeeCmd.Usage()
+				os.Exit(1)
+			}
+			cli.estimateFee(*estimateFeeBlocks)
+		}},
+		{chainInfoCmd, "Print the block count, best block and difficulty", cli.chainInfo},
+		{getTxCmd, "Print a transaction with its block, confirmations and fee", func() {
+			if *getTxID == "" {
+				getTxCmd.Usage()
+				os.Exit(1)
+			}
+			cli.getTx(*getTxID)
+		}},
+		{sweepCmd, "Move all funds of one address to another", func() {
+			if *sweepFrom == "" || *sweepTo == "" {
+				sweepCmd.Usage()
+				os.Exit(1)
+			}
+			cli.sweep(*sweepFrom, *sweepTo, *sweepMinConf)
+		}},
+		{buildTxIndexCmd, "Rebuild the transaction index", cli.buildTxIndex},
+		{getDifficultyCmd, "Print the difficulty of the next block", cli.getDifficulty},
+		{setDifficultyCmd, "Change the difficulty of subsequent blocks (requires -dev)", func() {
+			if *setDifficultyBits == 0 {
+				setDifficultyCmd.Usage()
+				os.Exit(1)
+			}
+			cli.setDifficulty(*setDifficultyBits)
+		}},
+		{merkleRootCmd, "Check a block's transactions against their IDs", func() {
+			if *merkleRootHash == "" {
+				merkleRootCmd.Usage()
+				os.Exit(1)
+			}
+			cli.merkleRoot(*merkleRootHash)
+		}},
+		{dumpUTXOsCmd, "List every unspent output on the chain", cli.dumpUTXOs},
+		{blockStatsCmd, "Print statistics for a block", func() {
+			if *blockStatsHash == "" {
+				blockStatsCmd.Usage()
+				os.Exit(1)
+			}
+			cli.blockStats(*blockStatsHash)
+		}},
+		{fingerprintCmd, "Print a hash identifying the whole chain", cli.fingerprint},
+		{getBlockHashCmd, "Print the hash of the block at a height", func() {
+			if *getBlockHashHeight < 0 {
+				getBlockHashCmd.Usage()
+				os.Exit(1)
+			}
+			cli.getBlockHash(*getBlockHashHeight)
+		}},
+		{getRawBlockCmd, "Print a block's serialized bytes in hex", func() {
+			if *getRawBlockHash == "" {
+				getRawBlockCmd.Usage()
+				os.Exit(1)
+			}
+			cli.getRawBlock(*getRawBlockHash, *getRawBlockJSON)
+		}},
+		{decodeRawBlockCmd, "Decode and print a block from getrawblock", func() {
+			if *decodeRawBlockHex == "" {
+				decodeRawBlockCmd.Usage()
+				os.Exit(1)
+			}
+			cli.decodeRawBlock(*decodeRawBlockHex)
+		}},
+		{rescanCmd, "Find every output ever paid to an address and its balance", func() {
+			if *rescanAddress == "" {
+				rescanCmd.Usage()
+				os.Exit(1)
+			}
+			cli.rescan(*rescanAddress)
+		}},
+		{resetCmd, "Delete the chain and start over from a new genesis block (requires -dev)", func() {
+			if *resetAddress == "" {
+				resetCmd.Usage()
+				os.Exit(1)
+			}
+			cli.reset(*resetAddress, *resetForce)
+		}},
+		{dbDumpCmd, "List the raw keys of a database bucket without changing anything", func() {
+			if *dbDumpBucket == "" {
+				dbDumpCmd.Usage()
+				os.Exit(1)
+			}
+			cli.dbDump(*dbDumpBucket)
+		}},
+		{consolidateCmd, "Combine an address's small outputs into a single output", func() {
+			if *consolidateAddress == "" {
+				consolidateCmd.Usage()
+				os.Exit(1)
+			}
+			cli.consolidate(*consolidateAddress, *consolidateThreshold, *consolidateMin)
+		}},
+		{exportHistoryCmd, "Write every transaction of an address to a CSV file", func() {
+			if *exportHistoryAddress == "" || *exportHistoryFile == "" {
+				exportHistoryCmd.Usage()
+				os.Exit(1)
+			}
+			cli.exportHistory(*exportHistoryAddress, *exportHistoryFile)
+		}},
+	}
+	for _, c := range commands {
+		c.setUsage()
+	}
+
+	globalFlags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	verbose := globalFlags.Bool("verbose", false, "Print mining progress in addition to the usual output")
+	quiet := globalFlags.Bool("quiet", false, "Print only final results")
+	dev := globalFlags.Bool("dev", false, "Development mode, required by -instant and setdifficulty")
+	instant := globalFlags.Bool("instant", false, "Seal blocks instantly instead of mining them (requires -dev)")
+	difficulty := globalFlags.Int("difficulty", 0, "Target bits for a new chain, 1 to 32; ignored for an existing chain")
+	auditLog := globalFlags.String("auditlog", "", "File to append a JSON line to for every block added")
+	globalFlags.Usage = func() {
+		printUsage(globalFlags.Output(), globalFlags, commands)
+	}
+
+	err := globalFlags.Parse(os.Args[1:])
+	if err != nil {
+		log.Panic(err)
+	}
+
+	args := globalFlags.Args()
+	if len(args) == 0 || (*verbose && *quiet) {
+		globalFlags.Usage()
+		os.Exit(1)
+	}
 
-	switch os.Args[1] {
-	case "addblock":
-		err := addBlockCmd.Parse(os.Args[2:])
+	level := blockchain.LevelInfo
+	switch {
+	case *verbose:
+		level = blockchain.LevelDebug
+	case *quiet:
+		level = blockchain.LevelError
+	}
+	blockchain.SetLogger(blockchain.NewStdLogger(cli.Out, level))
+
+	if *dev {
+		cli.options = append(cli.options, blockchain.WithDevMode())
+	}
+	if *instant {
+		cli.options = append(cli.options, blockchain.WithInstantSeal())
+	}
+	if *difficulty != 0 {
+		cli.options = append(cli.options, blockchain.WithDifficulty(*difficulty))
+	}
+	if *auditLog != "" {
+		cli.options = append(cli.options, blockchain.WithAuditLog(*auditLog, auditLogMaxSize))
+	}
+
+	if args[0] == "help" {
+		cli.help(args[1:], globalFlags, commands)
+		return
+	}
+
+	for _, c := range commands {
+		if c.flags.Name() != args[0] {
+			continue
+		}
+
+		err := c.flags.Parse(args[1:])
 		if err != nil {
 			log.Panic(err)
 		}
-	case "printchain":
-		err := printChainCmd.Parse(os.Args[2:])
+		c.run()
+
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "Unknown command '%s'\n\n", args[0])
+	globalFlags.Usage()
+	os.Exit(1)
+}
+
+// Prints the usage of the named command, or of everything without a name
+func (cli *CLI) help(args []string, globalFlags *flag.FlagSet, commands []command) {
+	if len(args) == 0 {
+		printUsage(cli.Out, globalFlags, commands)
+		return
+	}
+
+	for _, c := range commands {
+		if c.flags.Name() == args[0] {
+			c.printUsage(cli.Out)
+			return
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Unknown command '%s'\n\n", args[0])
+	printUsage(os.Stderr, globalFlags, commands)
+	os.Exit(1)
+}
+
+func (cli *CLI) openBlockchain(address string) *blockchain.Blockchain {
+	bc, err := blockchain.NewBlockchain(address, cli.options...)
+	if err != nil {
+		fmt.Fprintln(cli.Out, err)
+		os.Exit(1)
+	}
+
+	return bc
+}
+
+func (cli *CLI) createBlockchain(address, genesisData, premine, format string) {
+	storageFormats := map[string]blockchain.StorageFormat{
+		"gob":     blockchain.FormatGob,
+		"json":    blockchain.FormatJSON,
+		"compact": blockchain.FormatCompact,
+	}
+	storageFormat, ok := storageFormats[format]
+	if !ok {
+		fmt.Fprintf(cli.Out, "Unknown storage format '%s'\n", format)
+		os.Exit(1)
+	}
+
+	opts := append(cli.options, blockchain.WithStorageFormat(storageFormat))
+	if genesisData != "" {
+		opts = append(opts, blockchain.WithGenesisData(genesisData))
+	}
+	if premine != "" {
+		allocs, err := parseAllocations(premine)
+		if err != nil {
+			fmt.Fprintln(cli.Out, err)
+			os.Exit(1)
+		}
+		opts = append(opts, blockchain.WithAllocations(allocs))
+	}
+
+	bc, err := blockchain.CreateBlockchain(address, opts...)
+	if err != nil {
+		fmt.Fprintln(cli.Out, err)
+		os.Exit(1)
+	}
+	bc.Db.Close()
+	fmt.Fprintln(cli.Out, "Done!")
+}
+
+func (cli *CLI) repair() {
+	// opening the chain already repairs the tip
+	cli.options = append(cli.options, blockchain.WithTipRepair())
+	bc := cli.openBlockchain("")
+	defer bc.Db.Close()
+
+	if bc.TipRepaired() {
+		fmt.Fprintln(cli.Out, "Chain tip repaired")
+	} else {
+		fmt.Fprintln(cli.Out, "Chain tip is consistent")
+	}
+}
+
+func (cli *CLI) listUnspent(address string) {
+	bc := cli.openBlockchain(address)
+	defer bc.Db.Close()
+
+	outPoints, err := bc.UTXOsForAddress(address)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	total := 0
+	for _, out := range outPoints {
+		fmt.Fprintf(cli.Out, "%x:%d %d\n", out.Txid, out.Index, out.Value)
+		total += out.Value
+	}
+	fmt.Fprintf(cli.Out, "Total of '%s': %d\n", address, total)
+}
+
+func (cli *CLI) estimateFee(blocks int) {
+	bc := cli.openBlockchain("")
+	defer bc.Db.Close()
+
+	rate, err := bc.EstimateFeeRate(blocks)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	fmt.Fprintf(cli.Out, "Estimated fee rate to confirm within %d blocks: %d per 1000 bytes\n", blocks, rate)
+}
+
+func (cli *CLI) chainInfo() {
+	bc := cli.openBlockchain("")
+	defer bc.Db.Close()
+
+	count, err := bc.GetBlockCount()
+	if err != nil {
+		log.Panic(err)
+	}
+	tip, err := bc.Iterator().Next()
+	if err != nil {
+		log.Panic(err)
+	}
+	pow := blockchain.NewProofOfWork(tip)
+
+	fmt.Fprintf(cli.Out, "Blocks: %d\n", count)
+	fmt.Fprintf(cli.Out, "Best block: %x\n", bc.GetBestBlockHash())
+	fmt.Fprintf(cli.Out, "Difficulty: %d bits (~%d leading zero hex digits)\n", pow.TargetBits(), pow.TargetBits()/4)
+	fmt.Fprintf(cli.Out, "Difficulty multiplier: %.0f\n", pow.Difficulty())
+	fmt.Fprintf(cli.Out, "Target: %s\n", pow.TargetHex())
+	fmt.Fprintf(cli.Out, "Compact target: %08x\n", pow.CompactTarget())
+}
+
+func (cli *CLI) getTx(id string) {
+	txid, err := hex.DecodeString(id)
+	if err != nil {
+		fmt.Fprintln(cli.Out, "Transaction ID must be hex encoded")
+		os.Exit(1)
+	}
+
+	bc := cli.openBlockchain("")
+	defer bc.Db.Close()
+
+	tx, err := bc.FindTransaction(txid)
+	if err != nil {
+		fmt.Fprintln(cli.Out, err)
+		return
+	}
+
+	confirmations, err := bc.Confirmations(txid)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	fmt.Fprintln(cli.Out, tx)
+	if blockHash, err := bc.GetTransactionLocation(txid); err == nil {
+		fmt.Fprintf(cli.Out, "Block: %x\n", blockHash)
+	}
+	fmt.Fprintf(cli.Out, "Confirmations: %d\n", confirmations)
+
+	if !tx.IsCoinbase() {
+		fee, err := bc.TransactionFee(&tx)
 		if err != nil {
 			log.Panic(err)
 		}
-	case "createblockchain":
-		err := createBlockchainCmd.Parse(os.Args[2:])
+		rate, err := bc.FeePerKB(&tx)
 		if err != nil {
 			log.Panic(err)
 		}
-	default:
+		fmt.Fprintf(cli.Out, "Fee: %d (%d per 1000 bytes)\n", fee, rate)
+	}
+}
+
+func (cli *CLI) sweep(from, to string, minConfirmations int) {
+	cli.options = append(cli.options, blockchain.WithMinConfirmations(minConfirmations))
+	bc := cli.openBlockchain(from)
+	defer bc.Db.Close()
+
+	tx, err := blockchain.SweepAddress(from, to, bc)
+	if err != nil {
+		fmt.Fprintln(cli.Out, err)
+		return
+	}
+
+	fmt.Fprintf(cli.Out, "Swept %d to '%s' in transaction %x\n", tx.Vout[0].Value, to, tx.ID)
+	fmt.Fprintf(cli.Out, "The reward for mining the sweep block also went to '%s'\n", to)
+}
+
+func (cli *CLI) consolidate(address string, threshold, minOutputs int) {
+	bc := cli.openBlockchain(address)
+	defer bc.Db.Close()
+
+	tx, err := blockchain.ConsolidateDust(address, threshold, minOutputs, bc)
+	if err != nil {
+		fmt.Fprintln(cli.Out, err)
+		return
+	}
+
+	fmt.Fprintf(cli.Out, "Consolidated %d outputs into %d in transaction %x\n", len(tx.Vin), tx.Vout[0].Value, tx.ID)
+}
+
+func (cli *CLI) exportHistory(address, file string) {
+	bc := cli.openBlockchain(address)
+	defer bc.Db.Close()
+
+	entries, err := bc.TransactionsForAddress(address)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		log.Panic(err)
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"timestamp", "txid", "direction", "amount", "height", "confirmations"})
+	for _, e := range entries {
+		direction, amount := "in", e.Amount
+		if amount < 0 {
+			direction, amount = "out", -amount
+		}
+
+		w.Write([]string{
+			strconv.FormatInt(e.Timestamp, 10),
+			hex.EncodeToString(e.Txid),
+			direction,
+			strconv.Itoa(amount),
+			strconv.Itoa(e.Height),
+			strconv.Itoa(e.Confirmations),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		log.Panic(err)
+	}
+
+	fmt.Fprintf(cli.Out, "Wrote %d transactions to %s\n", len(entries), file)
+}
+
+func (cli *CLI) buildTxIndex() {
+	bc := cli.openBlockchain("")
+	defer bc.Db.Close()
+
+	count, err := bc.BuildTxIndex()
+	if err != nil {
+		log.Panic(err)
+	}
+
+	fmt.Fprintf(cli.Out, "Indexed %d transactions\n", count)
+}
+
+func (cli *CLI) getDifficulty() {
+	bc := cli.openBlockchain("")
+	defer bc.Db.Close()
+
+	fmt.Fprintf(cli.Out, "Difficulty: %d bits\n", bc.Difficulty())
+}
+
+func (cli *CLI) setDifficulty(bits int) {
+	bc := cli.openBlockchain("")
+	defer bc.Db.Close()
+
+	if err := bc.SetDifficulty(bits); err != nil {
+		fmt.Fprintln(cli.Out, err)
+		return
+	}
+
+	fmt.Fprintf(cli.Out, "Difficulty set to %d bits\n", bits)
+}
+
+// Blocks don't store a transactions root; they commit to HashTransactions
+// through the block hash. This prints that hash and checks each transaction
+// still matches its ID, which is what a tampered transaction breaks.
+func (cli *CLI) merkleRoot(hash string) {
+	blockHash, err := hex.DecodeString(hash)
+	if err != nil {
+		fmt.Fprintln(cli.Out, "Block hash must be hex encoded")
 		os.Exit(1)
 	}
 
-	if addBlockCmd.Parsed() {
-		if *addBlockData == "" {
-			addBlockCmd.Usage()
-			os.Exit(1)
+	bc := cli.openBlockchain("")
+	defer bc.Db.Close()
+
+	block, err := bc.GetBlock(blockHash)
+	if err != nil {
+		fmt.Fprintln(cli.Out, err)
+		return
+	}
+
+	fmt.Fprintf(cli.Out, "Transactions hash: %x\n", block.HashTransactions())
+
+	intact := true
+	for _, tx := range block.Transactions {
+		if !bytes.Equal(tx.Hash(), tx.ID) {
+			fmt.Fprintf(cli.Out, "Mismatch: transaction %x does not match its contents\n", tx.ID)
+			intact = false
 		}
-		cli.addBlock(*addBlockData)
 	}
+	if intact {
+		fmt.Fprintln(cli.Out, "All transactions match their IDs")
+	}
+}
+
+func (cli *CLI) dumpUTXOs() {
+	bc := cli.openBlockchain("")
+	defer bc.Db.Close()
 
-	if printChainCmd.Parsed() {
-		cli.printChain()
+	unspent, err := bc.UnspentOutputs()
+	if err != nil {
+		log.Panic(err)
 	}
 
-	if createBlockchainCmd.Parsed() {
-		if *createBlockchainAddress == "" {
-			createBlockchainCmd.Usage()
-			os.Exit(1)
+	total := 0
+	for _, out := range unspent {
+		fmt.Fprintf(cli.Out, "%x:%d %d %s\n", out.Txid, out.Index, out.Value, out.ScriptPubKey)
+		total += out.Value
+	}
+	fmt.Fprintf(cli.Out, "Unspent outputs: %d, total value: %d\n", len(unspent), total)
+}
+
+func (cli *CLI) blockStats(hash string) {
+	blockHash, err := hex.DecodeString(hash)
+	if err != nil {
+		fmt.Fprintln(cli.Out, "Block hash must be hex encoded")
+		os.Exit(1)
+	}
+
+	bc := cli.openBlockchain("")
+	defer bc.Db.Close()
+
+	stats, err := bc.BlockStats(blockHash)
+	if err != nil {
+		fmt.Fprintln(cli.Out, err)
+		return
+	}
+
+	fmt.Fprintf(cli.Out, "Transactions: %d\n", stats.Transactions)
+	fmt.Fprintf(cli.Out, "Input value: %d\n", stats.InputValue)
+	fmt.Fprintf(cli.Out, "Output value: %d\n", stats.OutputValue)
+	fmt.Fprintf(cli.Out, "Fees: %d\n", stats.Fees)
+	fmt.Fprintf(cli.Out, "Size: %d bytes\n", stats.Size)
+	fmt.Fprintf(cli.Out, "Merkle root: %x\n", stats.MerkleRoot)
+	fmt.Fprintf(cli.Out, "Difficulty: %.0f\n", stats.Difficulty)
+}
+
+func (cli *CLI) fingerprint() {
+	bc := cli.openBlockchain("")
+	defer bc.Db.Close()
+
+	fingerprint, err := bc.Fingerprint()
+	if err != nil {
+		log.Panic(err)
+	}
+
+	fmt.Fprintf(cli.Out, "%x\n", fingerprint)
+}
+
+func (cli *CLI) getBlockHash(height int) {
+	bc := cli.openBlockchain("")
+	defer bc.Db.Close()
+
+	hash, err := bc.GetBlockHash(height)
+	if err != nil {
+		fmt.Fprintln(cli.Out, err)
+		os.Exit(1)
+	}
+
+	fmt.Fprintf(cli.Out, "%x\n", hash)
+}
+
+func (cli *CLI) getRawBlock(hash string, asJSON bool) {
+	blockHash, err := hex.DecodeString(hash)
+	if err != nil {
+		fmt.Fprintln(cli.Out, "Block hash must be hex encoded")
+		os.Exit(1)
+	}
+
+	bc := cli.openBlockchain("")
+	defer bc.Db.Close()
+
+	block, err := bc.GetBlock(blockHash)
+	if err != nil {
+		fmt.Fprintln(cli.Out, err)
+		os.Exit(1)
+	}
+
+	if asJSON {
+		encoded, err := json.MarshalIndent(block, "", "  ")
+		if err != nil {
+			log.Panic(err)
 		}
-		cli.createBlockchain(*createBlockchainAddress)
+		fmt.Fprintln(cli.Out, string(encoded))
+		return
+	}
+
+	raw, err := block.Serialize()
+	if err != nil {
+		log.Panic(err)
 	}
+	fmt.Fprintln(cli.Out, hex.EncodeToString(raw))
 }
 
-func (cli *CLI) createBlockchain(address string) {
-	bc := blockchain.CreateBlockchain(address)
+func (cli *CLI) decodeRawBlock(rawHex string) {
+	raw, err := hex.DecodeString(strings.TrimSpace(rawHex))
+	if err != nil {
+		fmt.Fprintln(cli.Out, "Block must be hex encoded")
+		os.Exit(1)
+	}
+
+	block, err := blockchain.ReadBlockFrom(bytes.NewReader(raw))
+	if err != nil {
+		fmt.Fprintf(cli.Out, "Not a valid block: %s\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Fprintf(cli.Out, "Hash: %x\n", block.Hash)
+	fmt.Fprintf(cli.Out, "Prev. hash: %x\n", block.PrevBlockHash)
+	fmt.Fprintf(cli.Out, "Timestamp: %d\n", block.Timestamp)
+	fmt.Fprintf(cli.Out, "Nonce: %d\n", block.Nonce)
+	fmt.Fprintf(cli.Out, "Difficulty: %d\n", block.Difficulty)
+	for _, tx := range block.Transactions {
+		fmt.Fprintln(cli.Out)
+		fmt.Fprintln(cli.Out, tx)
+	}
+}
+
+func (cli *CLI) rescan(address string) {
+	bc := cli.openBlockchain(address)
+	defer bc.Db.Close()
+
+	scan, err := bc.Rescan(address)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	fmt.Fprintf(cli.Out, "Received outputs: %d\n", len(scan.Received))
+	for _, out := range scan.Received {
+		fmt.Fprintf(cli.Out, "  %x:%d %d\n", out.Txid, out.Index, out.Value)
+	}
+	fmt.Fprintf(cli.Out, "Unspent outputs: %d\n", len(scan.Unspent))
+	for _, out := range scan.Unspent {
+		fmt.Fprintf(cli.Out, "  %x:%d %d\n", out.Txid, out.Index, out.Value)
+	}
+	fmt.Fprintf(cli.Out, "Balance of '%s': %d\n", address, scan.Balance)
+}
+
+func (cli *CLI) reset(address string, force bool) {
+	if !force {
+		fmt.Fprintln(cli.Out, "This deletes every block of the chain. Run again with -force to go ahead")
+		os.Exit(1)
+	}
+
+	bc, err := blockchain.ResetBlockchain(address, cli.options...)
+	if err != nil {
+		fmt.Fprintln(cli.Out, err)
+		os.Exit(1)
+	}
 	bc.Db.Close()
-	fmt.Println("Done!")
+
+	fmt.Fprintln(cli.Out, "Done!")
+}
+
+func (cli *CLI) dbDump(bucket string) {
+	entries, err := blockchain.DumpBucket(bucket)
+	if err != nil {
+		fmt.Fprintln(cli.Out, err)
+		os.Exit(1)
+	}
+
+	for _, entry := range entries {
+		switch {
+		case bucket == "Blocks" && string(entry.Key) == "l":
+			fmt.Fprintf(cli.Out, "l (tip) -> %x\n", entry.Value)
+		case bucket == "Blocks":
+			fmt.Fprintf(cli.Out, "%x block record, %d bytes\n", entry.Key, len(entry.Value))
+		case isPrintable(entry.Key):
+			fmt.Fprintf(cli.Out, "%x (%q) %d bytes\n", entry.Key, entry.Key, len(entry.Value))
+		default:
+			fmt.Fprintf(cli.Out, "%x %d bytes\n", entry.Key, len(entry.Value))
+		}
+	}
+	fmt.Fprintf(cli.Out, "Keys: %d\n", len(entries))
+}
+
+// Parses "alice=100,bob=50" into address→amount allocations
+func parseAllocations(s string) (map[string]int, error) {
+	allocs := make(map[string]int)
+
+	for _, pair := range strings.Split(s, ",") {
+		address, amount, ok := strings.Cut(pair, "=")
+		if !ok || address == "" {
+			return nil, fmt.Errorf("premine entry '%s' is not address=amount", pair)
+		}
+
+		value, err := strconv.Atoi(amount)
+		if err != nil || value <= 0 {
+			return nil, fmt.Errorf("premine amount for '%s' must be a positive number", address)
+		}
+		allocs[address] += value
+	}
+
+	return allocs, nil
+}
+
+// Whether a key reads as text, like the names in the meta bucket
+func isPrintable(key []byte) bool {
+	for _, c := range key {
+		if c < 0x20 || c > 0x7e {
+			return false
+		}
+	}
+
+	return len(key) > 0
 }
 
 func (cli *CLI) addBlock(data string) {
 	//cli.Bc.AddBlock(data)
-	fmt.Println("Success!")
+	fmt.Fprintln(cli.Out, "Success!")
 }
 
 func (cli *CLI) printChain() {
-	bc := blockchain.NewBlockchain("")
+	bc := cli.openBlockchain("")
+	defer bc.Db.Close()
 	bci := bc.Iterator()
 
 	for {
-		block := bci.Next()
+		block, err := bci.Next()
+		if err != nil {
+			log.Panic(err)
+		}
 
-		fmt.Printf("Prev. hash: %x\n", block.PrevBlockHash)
-		fmt.Printf("Hash: %x\n", block.Hash)
-		pow := blockchain.NewProofOfWork(block)
-		fmt.Printf("PoW: %s\n", strconv.FormatBool(pow.Validate()))
-		fmt.Println()
+		fmt.Fprintf(cli.Out, "Prev. hash: %x\n", block.PrevBlockHash)
+		fmt.Fprintf(cli.Out, "Hash: %x\n", block.Hash)
+		fmt.Fprintf(cli.Out, "Seal valid: %s\n", strconv.FormatBool(bc.VerifySeal(block)))
+		fmt.Fprintln(cli.Out)
 
 		if len(block.PrevBlockHash) == 0 {
 			break
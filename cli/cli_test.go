@@ -0,0 +1,328 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go-blockchain/blockchain"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+// Runs the rest of the test in an empty directory, since the database path
+// is relative
+func chdirTemp(t *testing.T) {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Chdir(wd)
+	})
+}
+
+// Runs the command line given by args with -quiet and returns its output
+func runCLI(t *testing.T, args ...string) string {
+	t.Helper()
+
+	var out bytes.Buffer
+	runCLITo(t, &out, "-quiet", args...)
+
+	return out.String()
+}
+
+// Runs the command line given by args after the verbosity flag, "" for
+// the default, writing its output to out
+func runCLITo(t *testing.T, out io.Writer, verbosity string, args ...string) {
+	t.Helper()
+
+	if verbosity != "" {
+		args = append([]string{verbosity}, args...)
+	}
+	origArgs := os.Args
+	os.Args = append([]string{"blockchain"}, args...)
+	defer func() {
+		os.Args = origArgs
+	}()
+
+	cli := CLI{Out: out}
+	cli.Run()
+}
+
+// Creates a chain at 8 target bits in an empty directory, paying the genesis
+// reward to alice
+func newTestChain(t *testing.T) {
+	t.Helper()
+
+	chdirTemp(t)
+	runCLI(t, "-difficulty", "8", "createblockchain", "-address", "alice")
+	blockchain.SetLogger(blockchain.NopLogger)
+}
+
+// Returns the chain's tip block
+func tipBlock(t *testing.T) *blockchain.Block {
+	t.Helper()
+
+	bc, err := blockchain.NewBlockchain("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bc.Db.Close()
+
+	block, err := bc.GetBlock(bc.GetBestBlockHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return block
+}
+
+func TestMerkleRoot(t *testing.T) {
+	newTestChain(t)
+	block := tipBlock(t)
+	hash := fmt.Sprintf("%x", block.Hash)
+
+	out := runCLI(t, "merkleroot", "-hash", hash)
+	if !strings.Contains(out, "All transactions match their IDs") {
+		t.Errorf("merkleroot output for a healthy block:\n%s", out)
+	}
+
+	// rewrite the block with a tampered transaction, stored as a plain gob
+	// record like databases from before the record flag
+	block.Transactions[0].Vout[0].Value = 1000
+	record, err := block.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := bolt.Open("blockstore.db", 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("Blocks")).Put(block.Hash, record)
+	})
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out = runCLI(t, "merkleroot", "-hash", hash)
+	if !strings.Contains(out, "Mismatch: transaction") {
+		t.Errorf("merkleroot output for a tampered block:\n%s", out)
+	}
+}
+
+func TestRawBlockRoundTrip(t *testing.T) {
+	newTestChain(t)
+	block := tipBlock(t)
+	hash := fmt.Sprintf("%x", block.Hash)
+
+	raw := runCLI(t, "getrawblock", "-hash", hash)
+	out := runCLI(t, "decoderawblock", "-hex", raw)
+
+	for _, want := range []string{
+		"Hash: " + hash + "\n",
+		fmt.Sprintf("Timestamp: %d\n", block.Timestamp),
+		fmt.Sprintf("Nonce: %d\n", block.Nonce),
+		block.Transactions[0].String(),
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("decoderawblock output does not contain %q:\n%s", want, out)
+		}
+	}
+
+	var decoded blockchain.Block
+	if err := json.Unmarshal([]byte(runCLI(t, "getrawblock", "-hash", hash, "-json")), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded.Hash, block.Hash) || decoded.Nonce != block.Nonce || decoded.Bits != block.Bits {
+		t.Errorf("getrawblock -json = %+v, want %+v", decoded, *block)
+	}
+}
+
+func TestPrintChainWritesToOut(t *testing.T) {
+	newTestChain(t)
+	block := tipBlock(t)
+
+	out := runCLI(t, "printchain")
+
+	for _, want := range []string{
+		fmt.Sprintf("Hash: %x\n", block.Hash),
+		"Seal valid: true\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("printchain output does not contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestDBDumpListsGenesisAndTip(t *testing.T) {
+	newTestChain(t)
+	genesis := tipBlock(t)
+	before, err := os.ReadFile("blockstore.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := runCLI(t, "dbdump", "-bucket", "Blocks")
+
+	for _, want := range []string{
+		fmt.Sprintf("%x block record", genesis.Hash),
+		fmt.Sprintf("l (tip) -> %x\n", genesis.Hash),
+		"Keys: 2\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("dbdump output does not contain %q:\n%s", want, out)
+		}
+	}
+
+	after, err := os.ReadFile("blockstore.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Error("dbdump changed the database")
+	}
+}
+
+func TestPremineShowsInBalance(t *testing.T) {
+	chdirTemp(t)
+	runCLI(t, "-difficulty", "8", "createblockchain", "-address", "alice", "-premine", "bob=100,carol=50,bob=5")
+
+	for address, want := range map[string]int{"alice": 10, "bob": 105, "carol": 50, "dave": 0} {
+		out := runCLI(t, "listunspent", "-address", address)
+		if wantOut := fmt.Sprintf("Total of '%s': %d\n", address, want); !strings.Contains(out, wantOut) {
+			t.Errorf("listunspent output %q does not contain %q", out, wantOut)
+		}
+	}
+}
+
+func TestExportHistory(t *testing.T) {
+	newTestChain(t)
+	// bob receives the sweep and the reward for mining it, then sweeps
+	// both on to carol
+	runCLI(t, "sweep", "-from", "alice", "-to", "bob")
+	runCLI(t, "sweep", "-from", "bob", "-to", "carol")
+
+	bc, err := blockchain.NewBlockchain("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var blocks []*blockchain.Block
+	for height := 1; height <= 2; height++ {
+		hash, err := bc.GetBlockHash(height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block, err := bc.GetBlock(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		blocks = append(blocks, block)
+	}
+	bc.Db.Close()
+
+	row := func(block *blockchain.Block, tx int, direction string, amount, height, confirmations int) string {
+		return fmt.Sprintf("%d,%x,%s,%d,%d,%d\n", block.Timestamp, block.Transactions[tx].ID, direction, amount, height, confirmations)
+	}
+	header := "timestamp,txid,direction,amount,height,confirmations\n"
+
+	runCLI(t, "exporthistory", "-address", "bob", "-file", "bob.csv")
+	want := header +
+		row(blocks[0], 0, "in", 10, 1, 2) +
+		row(blocks[0], 1, "in", 9, 1, 2) +
+		row(blocks[1], 1, "out", 19, 2, 1)
+	if got, err := os.ReadFile("bob.csv"); err != nil || string(got) != want {
+		t.Errorf("bob.csv = %q, %v, want %q", got, err, want)
+	}
+
+	runCLI(t, "exporthistory", "-address", "dave", "-file", "dave.csv")
+	if got, err := os.ReadFile("dave.csv"); err != nil || string(got) != header {
+		t.Errorf("dave.csv = %q, %v, want only the header", got, err)
+	}
+}
+
+func TestChainInfoShowsDifficultyMultiplier(t *testing.T) {
+	newTestChain(t)
+
+	// 8 bits is 7 doublings above the easiest target
+	if out := runCLI(t, "chaininfo"); !strings.Contains(out, "Difficulty multiplier: 128\n") {
+		t.Errorf("chaininfo output does not show a multiplier of 128:\n%s", out)
+	}
+	hash := fmt.Sprintf("%x", tipBlock(t).Hash)
+	if out := runCLI(t, "blockstats", "-hash", hash); !strings.Contains(out, "Difficulty: 128\n") {
+		t.Errorf("blockstats output does not show a difficulty of 128:\n%s", out)
+	}
+}
+
+func TestQuietHidesMiningMessages(t *testing.T) {
+	chdirTemp(t)
+	defer blockchain.SetLogger(blockchain.NopLogger)
+
+	var out bytes.Buffer
+	runCLITo(t, &out, "", "-difficulty", "8", "createblockchain", "-address", "alice")
+	if !strings.Contains(out.String(), "Mining new block\n") {
+		t.Errorf("createblockchain output without -quiet does not mention mining:\n%s", out.String())
+	}
+
+	out.Reset()
+	runCLITo(t, &out, "-quiet", "sweep", "-from", "alice", "-to", "bob")
+	if strings.Contains(out.String(), "Mining new block") {
+		t.Errorf("sweep output with -quiet mentions mining:\n%s", out.String())
+	}
+}
+
+func TestVerboseShowsNonceProgress(t *testing.T) {
+	newTestChain(t)
+	defer blockchain.SetLogger(blockchain.NopLogger)
+
+	var out bytes.Buffer
+	runCLITo(t, &out, "-verbose", "getdifficulty")
+
+	// the logger -verbose installed reports progress on a search long
+	// enough to need it: none of this block's first MaxNonce nonces meet
+	// the hardest target
+	block := &blockchain.Block{Timestamp: 1700000000, Transactions: []*blockchain.Transaction{blockchain.NewCoinbaseTX("bob", "", 1)}, Difficulty: 32}
+	pow := blockchain.NewProofOfWork(block)
+	pow.MaxNonce = 100000
+	if _, _, err := pow.Run(); err != blockchain.ErrNonceExhausted {
+		t.Fatalf("Run() = %v, want ErrNonceExhausted", err)
+	}
+	if !strings.Contains(out.String(), "Tried 100000 nonces\n") {
+		t.Errorf("output with -verbose has no nonce progress:\n%s", out.String())
+	}
+
+	out.Reset()
+	runCLITo(t, &out, "", "getdifficulty")
+	pow = blockchain.NewProofOfWork(block)
+	pow.MaxNonce = 100000
+	if _, _, err := pow.Run(); err != blockchain.ErrNonceExhausted {
+		t.Fatalf("Run() = %v, want ErrNonceExhausted", err)
+	}
+	if strings.Contains(out.String(), "Tried") {
+		t.Errorf("output without -verbose has nonce progress:\n%s", out.String())
+	}
+}
+
+func TestChainInfoShowsTarget(t *testing.T) {
+	newTestChain(t)
+
+	out := runCLI(t, "chaininfo")
+	for _, want := range []string{
+		"Difficulty: 8 bits (~2 leading zero hex digits)\n",
+		"Target: 01" + strings.Repeat("0", 62) + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("chaininfo output does not contain %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// A subcommand with the one-line description shown in the help
+type command struct {
+	flags       *flag.FlagSet
+	description string
+
+	// checks the parsed flags and carries out the command
+	run func()
+}
+
+// Makes -h on the subcommand print its description along with its flags
+func (c command) setUsage() {
+	c.flags.Usage = func() {
+		c.printUsage(c.flags.Output())
+	}
+}
+
+func (c command) printUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s [global flags] %s [flags]\n\n%s\n", os.Args[0], c.flags.Name(), c.description)
+	if hasFlags(c.flags) {
+		fmt.Fprintln(w)
+		c.flags.SetOutput(w)
+		c.flags.PrintDefaults()
+	}
+}
+
+// Lists the global flags and every subcommand with its flags
+func printUsage(w io.Writer, global *flag.FlagSet, commands []command) {
+	fmt.Fprintf(w, "Usage: %s [global flags] <command> [flags]\n\nGlobal flags:\n", os.Args[0])
+	global.SetOutput(w)
+	global.PrintDefaults()
+
+	fmt.Fprintln(w, "\nCommands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-18s %s\n", c.flags.Name(), c.description)
+		c.flags.VisitAll(func(f *flag.Flag) {
+			fmt.Fprintf(w, "      -%-14s %s\n", f.Name, f.Usage)
+		})
+	}
+
+	fmt.Fprintf(w, "\nRun '%s help <command>' for details on a command.\n", os.Args[0])
+}
+
+func hasFlags(fs *flag.FlagSet) bool {
+	found := false
+	fs.VisitAll(func(*flag.Flag) {
+		found = true
+	})
+
+	return found
+}
@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestHelpListsCommands(t *testing.T) {
+	out := runCLI(t, "help")
+
+	commands := []string{
+		"addblock", "printchain", "createblockchain", "repair", "listunspent",
+		"estimatefee", "chaininfo", "gettx", "sweep", "buildtxindex",
+		"getdifficulty", "setdifficulty", "merkleroot", "dumputxos", "blockstats",
+		"fingerprint", "getblockhash", "getrawblock", "decoderawblock", "rescan",
+		"reset", "dbdump", "consolidate", "exporthistory",
+	}
+	for _, name := range commands {
+		if !strings.Contains(out, "\n  "+name+" ") {
+			t.Errorf("help does not list %s", name)
+		}
+	}
+
+	out = runCLI(t, "help", "sweep")
+	if !strings.Contains(out, "Move all funds of one address to another") || !strings.Contains(out, "-from") {
+		t.Errorf("help sweep output:\n%s", out)
+	}
+}
+
+func TestUnknownCommand(t *testing.T) {
+	// the CLI exits on an unknown command, so it runs in a child process
+	if os.Getenv("CLI_UNKNOWN_COMMAND") == "1" {
+		runCLI(t, "nosuchcommand")
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestUnknownCommand$")
+	cmd.Env = append(os.Environ(), "CLI_UNKNOWN_COMMAND=1")
+	out, err := cmd.CombinedOutput()
+
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+		t.Fatalf("unknown command exited with %v, want status 1", err)
+	}
+	if !strings.Contains(string(out), "Unknown command 'nosuchcommand'") || !strings.Contains(string(out), "Commands:") {
+		t.Errorf("unknown command output:\n%s", out)
+	}
+}